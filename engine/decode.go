@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/flac"
+	"github.com/gopxl/beep/mp3"
+	"github.com/gopxl/beep/wav"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Decode open the audio file, the format is told by the extension and wav is assumed for the unknown ones
+func Decode(path string) (beep.StreamSeekCloser, beep.Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	var (
+		streamer beep.StreamSeekCloser
+		format   beep.Format
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		streamer, format, err = mp3.Decode(f)
+	case ".flac":
+		streamer, format, err = flac.Decode(f)
+	default:
+		streamer, format, err = wav.Decode(f)
+	}
+	if err != nil {
+		f.Close()
+		return nil, beep.Format{}, err
+	}
+	return streamer, format, nil
+}
+
+// openTrack decode the track, a virtual track only stream its part of the file
+func openTrack(track Track) (beep.StreamSeekCloser, beep.Format, error) {
+	streamer, format, err := Decode(track.Path)
+	if err != nil {
+		return nil, format, err
+	}
+	if track.Start == 0 && track.End == 0 {
+		return streamer, format, nil
+	}
+	segmented, err := newSegment(streamer, format.SampleRate.N(track.Start), format.SampleRate.N(track.End))
+	if err != nil {
+		streamer.Close()
+		return nil, format, err
+	}
+	return segmented, format, nil
+}
+
+// segment limit the streamer to the samples in [start, end), position and length are relative to start
+type segment struct {
+	beep.StreamSeekCloser
+	start int
+	end   int
+}
+
+func newSegment(streamer beep.StreamSeekCloser, start, end int) (*segment, error) {
+	if end <= 0 || end > streamer.Len() {
+		end = streamer.Len()
+	}
+	start = min(max(start, 0), end)
+	if err := streamer.Seek(start); err != nil {
+		return nil, err
+	}
+	return &segment{streamer, start, end}, nil
+}
+
+func (s *segment) Stream(samples [][2]float64) (int, bool) {
+	remaining := s.end - s.StreamSeekCloser.Position()
+	if remaining <= 0 {
+		return 0, false
+	}
+	if len(samples) > remaining {
+		samples = samples[:remaining]
+	}
+	return s.StreamSeekCloser.Stream(samples)
+}
+
+func (s *segment) Len() int {
+	return s.end - s.start
+}
+
+func (s *segment) Position() int {
+	return s.StreamSeekCloser.Position() - s.start
+}
+
+func (s *segment) Seek(p int) error {
+	return s.StreamSeekCloser.Seek(min(max(p, 0), s.Len()) + s.start)
+}
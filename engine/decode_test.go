@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// rampStreamer stream its own sample position, so the test can tell where samples come from
+type rampStreamer struct {
+	position int
+	length   int
+}
+
+func (r *rampStreamer) Stream(samples [][2]float64) (int, bool) {
+	n := 0
+	for n < len(samples) && r.position < r.length {
+		samples[n] = [2]float64{float64(r.position), float64(r.position)}
+		r.position++
+		n++
+	}
+	return n, n > 0
+}
+
+func (r *rampStreamer) Err() error       { return nil }
+func (r *rampStreamer) Len() int         { return r.length }
+func (r *rampStreamer) Position() int    { return r.position }
+func (r *rampStreamer) Seek(p int) error { r.position = p; return nil }
+func (r *rampStreamer) Close() error     { return nil }
+
+func TestSegment(t *testing.T) {
+	t.Run("segment", func(t *testing.T) {
+		s, err := newSegment(&rampStreamer{length: 100}, 10, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, s.Len())
+		assert.Equal(t, 0, s.Position())
+		samples := make([][2]float64, 16)
+		n, ok := s.Stream(samples)
+		assert.True(t, ok)
+		assert.Equal(t, 10, n)
+		assert.Equal(t, [2]float64{10, 10}, samples[0])
+		assert.Equal(t, [2]float64{19, 19}, samples[9])
+		_, ok = s.Stream(samples)
+		assert.False(t, ok)
+		assert.NoError(t, s.Seek(5))
+		assert.Equal(t, 5, s.Position())
+		n, _ = s.Stream(samples)
+		assert.Equal(t, 5, n)
+		assert.Equal(t, [2]float64{15, 15}, samples[0])
+	})
+	t.Run("segment until end of file", func(t *testing.T) {
+		s, err := newSegment(&rampStreamer{length: 100}, 90, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, s.Len())
+	})
+}
@@ -0,0 +1,523 @@
+// Package engine play a queue of audio tracks without any UI, the state is reported to the subscribers as events
+package engine
+
+import (
+	"errors"
+	"github.com/gopxl/beep"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoTrack = errors.New("engine: no track to play")
+	ErrClosed  = errors.New("engine: closed")
+)
+
+// sampleRate of the output, tracks of other rates are resampled
+const sampleRate beep.SampleRate = 44100
+
+// positionInterval is how often Ticked is sent while playing, several times a second so the progress move smoothly
+const positionInterval = 200 * time.Millisecond
+
+type State int
+
+const (
+	Stopped State = iota // nothing loaded
+	Loading
+	Playing
+	Paused
+	Ended // the only track of the queue finished, Play start it again
+)
+
+func (s State) String() string {
+	return [...]string{"stopped", "loading", "playing", "paused", "ended"}[s]
+}
+
+// Track is an audio file, or the part of it from Start to End for a virtual track of a cue sheet
+type Track struct {
+	Path  string
+	Start time.Duration
+	End   time.Duration // 0 until the end of the file
+}
+
+type EventKind int
+
+const (
+	StateChanged EventKind = iota
+	TrackChanged           // a track is loaded, Index and Duration describe it
+	TrackLeft              // the current track is left for another one, because it ended or the engine closed, Position tell how far it got
+	Ticked                 // the playback moved on, sent every positionInterval while playing
+	Seeked                 // a seek is applied, Seek is its id
+	Failed                 // loading a track failed, Err tell why
+)
+
+// Event is a snapshot of the engine when something happened
+type Event struct {
+	Kind     EventKind
+	State    State
+	Track    Track
+	Index    int // index of Track in the queue, -1 when it is no longer in the queue
+	Position time.Duration
+	Duration time.Duration
+	Seek     uint64 // id of the last applied seek, a position from before a requested seek is applied is stale
+	Err      error
+}
+
+type Engine struct {
+	mu            sync.Mutex
+	out           Output
+	initialized   bool // whether the output is initialized
+	closed        bool
+	queue         []Track
+	index         int
+	track         Track
+	state         State
+	play          bool // whether the loading track start playing once loaded
+	streamer      beep.StreamSeekCloser
+	format        beep.Format
+	ctrl          *beep.Ctrl
+	speed         float64
+	speedMode     SpeedMode
+	speedStreamer *speedStreamer
+	tap           *Tap
+	gain          GainFunc
+	gains         uint64  // counted up on every SetGain, so a gain computed for a replaced func is ignored
+	trackGain     float64 // dB of the loaded track
+	gainStreamer  *gainStreamer
+	equalizer     Equalizer
+	eqStreamer    *equalizerStreamer // equalizer of the playing track
+	generation    int                // counted up on every load, so the end of a replaced track is ignored
+	seeks         uint64             // id of the last seek requested
+	seeked        uint64             // id of the last seek applied
+	pendingSeek   *seekRequest       // latest request not applied yet, older ones are dropped
+	seekWake      chan struct{}
+	subscribers   map[int]func(Event)
+	nextID        int
+	pending       []Event
+	wake          chan struct{}
+	done          chan struct{}
+}
+
+// New create an engine playing through the speaker
+func New() *Engine {
+	return NewWithOutput(Speaker{})
+}
+
+// NewWithOutput create an engine playing through out, such as a sink when there is no sound device
+func NewWithOutput(out Output) *Engine {
+	e := &Engine{
+		out:         out,
+		index:       -1,
+		speed:       1,
+		tap:         &Tap{},
+		subscribers: map[int]func(Event){},
+		wake:        make(chan struct{}, 1),
+		seekWake:    make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	go e.dispatch()
+	go e.tick()
+	go e.seeker()
+	return e
+}
+
+// Subscribe call fn with every event in order, from a goroutine of the engine. Call the returned func to stop
+func (e *Engine) Subscribe(fn func(Event)) (unsubscribe func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := e.nextID
+	e.nextID++
+	e.subscribers[id] = fn
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.subscribers, id)
+	}
+}
+
+// Load replace the queue and load the track at index, paused at its start
+func (e *Engine) Load(tracks []Track, index int) error {
+	e.mu.Lock()
+	e.queue = slices.Clone(tracks)
+	e.mu.Unlock()
+	return e.load(index, false)
+}
+
+// SetQueue replace the queue without interrupting the current track, index is where the current track is in it, -1 when it is not
+func (e *Engine) SetQueue(tracks []Track, index int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queue = slices.Clone(tracks)
+	e.index = index
+}
+
+// Play resume the current track, a stopped engine load the current track of the queue again
+func (e *Engine) Play() error {
+	e.mu.Lock()
+	if e.state == Stopped {
+		index := max(e.index, 0)
+		e.mu.Unlock()
+		return e.load(index, true)
+	}
+	defer e.mu.Unlock()
+	switch e.state {
+	case Loading:
+		e.play = true
+	case Paused:
+		e.setPaused(false)
+		e.setState(Playing)
+	case Ended:
+		e.setPaused(false)
+		e.start()
+		e.setState(Playing)
+	}
+	return nil
+}
+
+func (e *Engine) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch e.state {
+	case Loading:
+		e.play = false
+	case Playing:
+		e.setPaused(true)
+		e.setState(Paused)
+	}
+}
+
+type seekRequest struct {
+	id       uint64
+	position time.Duration
+}
+
+// Seek move to the position of the current track right away, clamped to the track
+func (e *Engine) Seek(position time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seeks++
+	return e.seek(seekRequest{e.seeks, position})
+}
+
+// RequestSeek ask to move to the position of the current track without waiting, for a dragged slider.
+// Requests are applied in background and coalesced, only the latest of the requests made meanwhile is applied.
+// The Seeked event of the applied request acknowledge the dropped ones too, as ids only grow
+func (e *Engine) RequestSeek(position time.Duration) (id uint64) {
+	e.mu.Lock()
+	e.seeks++
+	e.pendingSeek = &seekRequest{e.seeks, position}
+	id = e.seeks
+	e.mu.Unlock()
+	select {
+	case e.seekWake <- struct{}{}:
+	default:
+	}
+	return id
+}
+
+func (e *Engine) seeker() {
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-e.seekWake:
+		}
+		e.mu.Lock()
+		if request := e.pendingSeek; request != nil && request.id > e.seeked {
+			e.seek(*request)
+		}
+		e.pendingSeek = nil
+		e.mu.Unlock()
+	}
+}
+
+// seek apply the request, it is acknowledged even when nothing is loaded
+func (e *Engine) seek(request seekRequest) error {
+	e.seeked = request.id
+	var err error
+	if e.streamer == nil {
+		err = ErrNoTrack
+	} else {
+		e.out.Lock()
+		n := min(max(e.format.SampleRate.N(request.position), 0), e.streamer.Len())
+		if err = e.streamer.Seek(n); err == nil {
+			e.speedStreamer.reset(n)
+		}
+		e.out.Unlock()
+	}
+	e.emit(Seeked, err)
+	return err
+}
+
+// Next play the track after the current one in the queue
+func (e *Engine) Next() error {
+	e.mu.Lock()
+	index := e.index + 1
+	e.mu.Unlock()
+	return e.load(index, true)
+}
+
+// Prev play the track before the current one in the queue
+func (e *Engine) Prev() error {
+	e.mu.Lock()
+	index := e.index - 1
+	e.mu.Unlock()
+	return e.load(index, true)
+}
+
+// SetEqualizer change the equalizer of the playback, a change while playing is crossfaded so it does not click
+func (e *Engine) SetEqualizer(eq Equalizer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.equalizer = eq
+	if e.eqStreamer != nil {
+		e.out.Lock()
+		e.eqStreamer.set(eq)
+		e.out.Unlock()
+	}
+}
+
+// Tap is where the latest played samples are kept, after the pause control so a paused track is silent
+func (e *Engine) Tap() *Tap {
+	return e.tap
+}
+
+func (e *Engine) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state
+}
+
+// Position of the current track, 0 when nothing is loaded
+func (e *Engine) Position() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.position()
+}
+
+// Duration of the current track, 0 when nothing is loaded
+func (e *Engine) Duration() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.duration()
+}
+
+// Close stop playing and close the output, the engine can not be used anymore
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	e.generation++
+	e.unload()
+	e.setState(Stopped)
+	close(e.done)
+	if e.initialized {
+		return e.out.Close()
+	}
+	return nil
+}
+
+// load replace the current track by the one at index of the queue, the file is decoded without holding the lock
+func (e *Engine) load(index int, play bool) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return ErrClosed
+	}
+	if index < 0 || index >= len(e.queue) {
+		e.mu.Unlock()
+		return ErrNoTrack
+	}
+	e.generation++
+	generation := e.generation
+	e.unload()
+	e.track, e.index, e.play = e.queue[index], index, play
+	e.setState(Loading)
+	track, queue, gain := e.track, e.queue, e.gain
+	e.mu.Unlock()
+
+	streamer, format, err := openTrack(track)
+	var db float64
+	if err == nil {
+		db = trackGain(gain, queue, index)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if generation != e.generation { // replaced while decoding
+		if err == nil {
+			streamer.Close()
+		}
+		return nil
+	}
+	if err == nil && !e.initialized {
+		if err = e.out.Init(sampleRate); err == nil {
+			e.initialized = true
+		} else {
+			streamer.Close()
+		}
+	}
+	if err != nil {
+		e.setState(Stopped)
+		e.emit(Failed, err)
+		return err
+	}
+	e.streamer, e.format, e.trackGain = streamer, format, db
+	e.speedStreamer = newSpeedStreamer(streamer, format.SampleRate, e.speed, e.speedMode)
+	e.ctrl = &beep.Ctrl{Streamer: e.speedStreamer, Paused: !e.play}
+	e.emit(TrackChanged, nil)
+	e.start()
+	if e.play {
+		e.setState(Playing)
+	} else {
+		e.setState(Paused)
+	}
+	return nil
+}
+
+// start send the current track to the output through the speed, the gain, the equalizer and the tap, calling ended once it is drained
+func (e *Engine) start() {
+	var s beep.Streamer = e.ctrl
+	if e.format.SampleRate != sampleRate {
+		s = beep.Resample(4, e.format.SampleRate, sampleRate, s)
+	}
+	e.gainStreamer = newGainStreamer(s, e.trackGain, sampleRate)
+	e.eqStreamer = newEqualizerStreamer(e.gainStreamer, e.equalizer, sampleRate)
+	s = &tapStreamer{s: e.eqStreamer, tap: e.tap}
+	generation := e.generation
+	e.out.Play(beep.Seq(s, beep.Callback(func() {
+		// called by the output under its lock, which load and ended take after the engine lock
+		go e.ended(generation)
+	})))
+}
+
+// ended move to the next track of the queue, wrapping around. The only track of a queue rewind and wait
+func (e *Engine) ended(generation int) {
+	e.mu.Lock()
+	if generation != e.generation {
+		e.mu.Unlock()
+		return
+	}
+	if len(e.queue) > 1 {
+		index := (e.index + 1) % len(e.queue)
+		e.mu.Unlock()
+		e.load(index, true)
+		return
+	}
+	defer e.mu.Unlock()
+	e.emit(TrackLeft, nil)
+	e.out.Lock()
+	e.streamer.Seek(0)
+	e.speedStreamer.reset(0)
+	e.ctrl.Paused = true
+	e.out.Unlock()
+	e.tap.clear()
+	e.setState(Ended)
+}
+
+// unload stop and close the current track
+func (e *Engine) unload() {
+	if e.streamer == nil {
+		return
+	}
+	e.emit(TrackLeft, nil)
+	e.out.Clear()
+	e.tap.clear()
+	e.streamer.Close()
+	e.streamer, e.ctrl, e.speedStreamer, e.gainStreamer, e.eqStreamer = nil, nil, nil, nil, nil
+}
+
+func (e *Engine) setPaused(paused bool) {
+	e.out.Lock()
+	e.ctrl.Paused = paused
+	e.out.Unlock()
+}
+
+func (e *Engine) setState(state State) {
+	if e.state != state {
+		e.state = state
+		e.emit(StateChanged, nil)
+	}
+}
+
+func (e *Engine) position() time.Duration {
+	if e.streamer == nil {
+		return 0
+	}
+	e.out.Lock()
+	defer e.out.Unlock()
+	// the speed read ahead of what is played
+	return e.format.SampleRate.D(min(int(e.speedStreamer.position), e.streamer.Position()))
+}
+
+func (e *Engine) duration() time.Duration {
+	if e.streamer == nil {
+		return 0
+	}
+	return e.format.SampleRate.D(e.streamer.Len())
+}
+
+// emit queue the event for the subscribers, the lock is held so the events keep their order
+func (e *Engine) emit(kind EventKind, err error) {
+	e.pending = append(e.pending, Event{
+		Kind:     kind,
+		State:    e.state,
+		Track:    e.track,
+		Index:    e.index,
+		Position: e.position(),
+		Duration: e.duration(),
+		Seek:     e.seeked,
+		Err:      err,
+	})
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch deliver the queued events until the engine is closed, the events queued by Close included
+func (e *Engine) dispatch() {
+	for {
+		var closed bool
+		select {
+		case <-e.done:
+			closed = true
+		case <-e.wake:
+		}
+		e.mu.Lock()
+		events := e.pending
+		e.pending = nil
+		subscribers := slices.Collect(maps.Values(e.subscribers))
+		e.mu.Unlock()
+		for _, event := range events {
+			for _, fn := range subscribers {
+				fn(event)
+			}
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+func (e *Engine) tick() {
+	ticker := time.NewTicker(positionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			if e.state == Playing {
+				e.emit(Ticked, nil)
+			}
+			e.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/wav"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOutput mix the played streamers like the speaker, the test pull the samples instead of a sound card
+type fakeOutput struct {
+	mu     sync.Mutex
+	mixer  beep.Mixer
+	inited beep.SampleRate
+	closed bool
+}
+
+func (o *fakeOutput) Init(rate beep.SampleRate) error { o.inited = rate; return nil }
+func (o *fakeOutput) Play(s beep.Streamer)            { o.Lock(); o.mixer.Add(s); o.Unlock() }
+func (o *fakeOutput) Clear()                          { o.Lock(); o.mixer.Clear(); o.Unlock() }
+func (o *fakeOutput) Lock()                           { o.mu.Lock() }
+func (o *fakeOutput) Unlock()                         { o.mu.Unlock() }
+func (o *fakeOutput) Close() error                    { o.closed = true; return nil }
+
+// pull stream the duration of output samples
+func (o *fakeOutput) pull(d time.Duration) {
+	o.Lock()
+	defer o.Unlock()
+	o.mixer.Stream(make([][2]float64, sampleRate.N(d)))
+}
+
+// writeWAV create a silent wav of the duration
+func writeWAV(t *testing.T, path string, rate beep.SampleRate, d time.Duration) string {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, wav.Encode(f, beep.Silence(rate.N(d)), beep.Format{SampleRate: rate, NumChannels: 2, Precision: 2}))
+	return path
+}
+
+// recorder collect the events of the engine
+type recorder struct {
+	events chan Event
+}
+
+func record(e *Engine) *recorder {
+	r := &recorder{events: make(chan Event, 256)}
+	e.Subscribe(func(event Event) { r.events <- event })
+	return r
+}
+
+// wait return the next event of the kind, failing when it does not come
+func (r *recorder) wait(t *testing.T, kind EventKind) Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-r.events:
+			if event.Kind == kind {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no event of kind %d", kind)
+			return Event{}
+		}
+	}
+}
+
+// waitState return the next state change to the state
+func (r *recorder) waitState(t *testing.T, state State) Event {
+	t.Helper()
+	for {
+		event := r.wait(t, StateChanged)
+		if event.State == state {
+			return event
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []Track{
+		{Path: writeWAV(t, filepath.Join(dir, "a.wav"), 44100, time.Second)},
+		{Path: writeWAV(t, filepath.Join(dir, "b.wav"), 22050, 2*time.Second)},
+		{Path: writeWAV(t, filepath.Join(dir, "c.wav"), 44100, 3*time.Second), Start: time.Second, End: 2 * time.Second},
+	}
+
+	t.Run("load, play and pause", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.Equal(t, Stopped, e.State())
+		assert.NoError(t, e.Load(tracks, 1))
+		assert.Equal(t, Loading, r.wait(t, StateChanged).State)
+		changed := r.wait(t, TrackChanged)
+		assert.Equal(t, 1, changed.Index)
+		assert.Equal(t, tracks[1], changed.Track)
+		assert.Equal(t, 2*time.Second, changed.Duration)
+		assert.Equal(t, Paused, r.wait(t, StateChanged).State)
+		assert.Equal(t, beep.SampleRate(44100), out.inited)
+
+		out.pull(time.Second / 2)
+		assert.Equal(t, time.Duration(0), e.Position(), "paused does not move")
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		out.pull(time.Second / 2)
+		assert.InDelta(t, time.Second/2, e.Position(), float64(20*time.Millisecond), "resampled from 22050, the resampler read ahead a little")
+		e.Pause()
+		r.waitState(t, Paused)
+		assert.NoError(t, e.Seek(1500*time.Millisecond))
+		assert.Equal(t, 1500*time.Millisecond, r.wait(t, Seeked).Position)
+		assert.NoError(t, e.Seek(time.Hour))
+		assert.Equal(t, 2*time.Second, e.Position(), "clamped to the track")
+	})
+	t.Run("next, prev and queue end", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		assert.ErrorIs(t, e.Prev(), ErrNoTrack)
+		assert.NoError(t, e.Next())
+		left := r.wait(t, TrackLeft)
+		assert.Equal(t, 0, left.Index)
+		assert.Equal(t, 1, r.wait(t, TrackChanged).Index)
+		assert.Equal(t, Playing, e.State(), "next keep playing")
+		assert.NoError(t, e.Next())
+		changed := r.wait(t, TrackChanged)
+		assert.Equal(t, 2, changed.Index)
+		assert.Equal(t, time.Second, changed.Duration, "virtual track")
+		assert.ErrorIs(t, e.Next(), ErrNoTrack)
+
+		out.pull(1100 * time.Millisecond) // the last track end and the queue wrap around
+		left = r.wait(t, TrackLeft)
+		assert.Equal(t, 2, left.Index)
+		assert.Equal(t, time.Second, left.Position)
+		assert.Equal(t, 0, r.wait(t, TrackChanged).Index)
+		r.waitState(t, Playing)
+	})
+	t.Run("single track end", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks[:1], 0))
+		assert.NoError(t, e.Play())
+		out.pull(1100 * time.Millisecond)
+		r.waitState(t, Ended)
+		assert.Equal(t, time.Duration(0), e.Position(), "rewound")
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		out.pull(time.Second / 2)
+		assert.InDelta(t, time.Second/2, e.Position(), float64(10*time.Millisecond))
+	})
+	t.Run("set queue", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		r.waitState(t, Paused)
+		e.SetQueue([]Track{tracks[2], tracks[0]}, 1)
+		assert.ErrorIs(t, e.Next(), ErrNoTrack)
+		assert.NoError(t, e.Prev())
+		assert.Equal(t, tracks[2], r.wait(t, TrackChanged).Track)
+	})
+	t.Run("failed load", func(t *testing.T) {
+		e := NewWithOutput(&fakeOutput{})
+		defer e.Close()
+		r := record(e)
+		assert.Error(t, e.Load([]Track{{Path: filepath.Join(dir, "missing.wav")}}, 0))
+		assert.Error(t, r.wait(t, Failed).Err)
+		assert.Equal(t, Stopped, e.State())
+		assert.ErrorIs(t, e.Seek(0), ErrNoTrack)
+	})
+	t.Run("close", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		r.waitState(t, Paused)
+		e.Close()
+		r.wait(t, TrackLeft)
+		r.waitState(t, Stopped)
+		assert.True(t, out.closed)
+		assert.ErrorIs(t, e.Play(), ErrClosed)
+		e.Close()
+	})
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"math"
+	"time"
+)
+
+const (
+	EqualizerBands = 10
+	equalizerQ     = 1.41 // about an octave wide, so neighbouring bands overlap smoothly
+	equalizerFade  = 20 * time.Millisecond
+)
+
+// EqualizerFrequencies are the centers of the bands in Hz
+var EqualizerFrequencies = [EqualizerBands]float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+// Equalizer is a graphic equalizer setting, gains are in dB and the zero value is flat
+type Equalizer struct {
+	Preamp float64                 `json:"preamp"`
+	Gains  [EqualizerBands]float64 `json:"gains"`
+}
+
+// biquad is a peaking filter of the audio eq cookbook, in transposed direct form II
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z                  [2][2]float64 // state of the left and right channel
+}
+
+func peaking(frequency, gain float64, rate beep.SampleRate) biquad {
+	a := math.Pow(10, gain/40)
+	w := 2 * math.Pi * frequency / float64(rate)
+	alpha := math.Sin(w) / (2 * equalizerQ)
+	a0 := 1 + alpha/a
+	return biquad{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * math.Cos(w) / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * math.Cos(w) / a0,
+		a2: (1 - alpha/a) / a0,
+	}
+}
+
+func (f *biquad) process(channel int, x float64) float64 {
+	z := &f.z[channel]
+	y := f.b0*x + z[0]
+	z[0] = f.b1*x - f.a1*y + z[1]
+	z[1] = f.b2*x - f.a2*y
+	return y
+}
+
+// equalizerFilters apply a setting, the preamp then the bands
+type equalizerFilters struct {
+	gain  float64
+	bands [EqualizerBands]biquad
+}
+
+func newEqualizerFilters(eq Equalizer, rate beep.SampleRate) *equalizerFilters {
+	f := &equalizerFilters{gain: math.Pow(10, eq.Preamp/20)}
+	for i, frequency := range EqualizerFrequencies {
+		f.bands[i] = peaking(frequency, eq.Gains[i], rate)
+	}
+	return f
+}
+
+func (f *equalizerFilters) process(sample [2]float64) [2]float64 {
+	for c := range 2 {
+		x := sample[c] * f.gain
+		for i := range f.bands {
+			x = f.bands[i].process(c, x)
+		}
+		sample[c] = x
+	}
+	return sample
+}
+
+// equalizerStreamer filter the stream. A new setting is crossfaded in over equalizerFade so it never click,
+// the settings made meanwhile are coalesced and the latest is faded in next
+type equalizerStreamer struct {
+	s       beep.Streamer
+	rate    beep.SampleRate
+	flat    bool // the current setting is flat, the samples pass through
+	current *equalizerFilters
+	next    *equalizerFilters // faded in, nil when not fading
+	pending *Equalizer        // set while fading
+	faded   int               // samples of the fade done
+}
+
+func newEqualizerStreamer(s beep.Streamer, eq Equalizer, rate beep.SampleRate) *equalizerStreamer {
+	return &equalizerStreamer{s: s, rate: rate, flat: eq == Equalizer{}, current: newEqualizerFilters(eq, rate)}
+}
+
+// set is called under the output lock
+func (q *equalizerStreamer) set(eq Equalizer) {
+	q.pending = &eq
+	if q.next == nil {
+		q.fade()
+	}
+}
+
+// fade start fading the pending setting in, the new filters start from the state of the current ones
+func (q *equalizerStreamer) fade() {
+	next := newEqualizerFilters(*q.pending, q.rate)
+	for i := range next.bands {
+		next.bands[i].z = q.current.bands[i].z
+	}
+	q.flat = *q.pending == Equalizer{}
+	q.next, q.pending, q.faded = next, nil, 0
+}
+
+func (q *equalizerStreamer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := q.s.Stream(samples)
+	if q.flat && q.next == nil {
+		return n, ok
+	}
+	length := q.rate.N(equalizerFade)
+	for i, sample := range samples[:n] {
+		y := q.current.process(sample)
+		if q.next != nil {
+			t := float64(q.faded) / float64(length)
+			z := q.next.process(sample)
+			for c := range 2 {
+				y[c] = y[c]*(1-t) + z[c]*t
+			}
+			q.faded++
+			if q.faded == length {
+				q.current, q.next = q.next, nil
+				if q.flat { // bypassed from now, so drop the state for the next fade to start from silence
+					for b := range q.current.bands {
+						q.current.bands[b].z = [2][2]float64{}
+					}
+				}
+				if q.pending != nil {
+					q.fade()
+				}
+			}
+		}
+		samples[i] = y
+	}
+	return n, ok
+}
+
+func (q *equalizerStreamer) Err() error {
+	return q.s.Err()
+}
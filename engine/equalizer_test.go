@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sineStreamer is an endless sine of the frequency at sampleRate
+func sineStreamer(frequency float64) beep.Streamer {
+	n := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			value := 0.25 * math.Sin(2*math.Pi*frequency*float64(n)/float64(sampleRate))
+			samples[i] = [2]float64{value, value}
+			n++
+		}
+		return len(samples), true
+	})
+}
+
+// amplitude of the sine once the filters settled
+func amplitude(s beep.Streamer) float64 {
+	samples := make([][2]float64, sampleRate.N(equalizerFade)*20)
+	s.Stream(samples)
+	var peak float64
+	for _, sample := range samples[len(samples)/2:] {
+		peak = max(peak, math.Abs(sample[0]))
+	}
+	return peak
+}
+
+func TestEqualizer(t *testing.T) {
+	t.Run("flat pass through", func(t *testing.T) {
+		samples := make([][2]float64, 100)
+		newEqualizerStreamer(sineStreamer(1000), Equalizer{}, sampleRate).Stream(samples)
+		want := make([][2]float64, 100)
+		sineStreamer(1000).Stream(want)
+		assert.Equal(t, want, samples)
+	})
+	t.Run("band gain", func(t *testing.T) {
+		var eq Equalizer
+		eq.Gains[5] = 6 // 1 kHz
+		boosted := amplitude(newEqualizerStreamer(sineStreamer(1000), eq, sampleRate))
+		assert.InDelta(t, 0.25*math.Pow(10, 6.0/20), boosted, 0.01)
+		away := amplitude(newEqualizerStreamer(sineStreamer(16000), eq, sampleRate))
+		assert.InDelta(t, 0.25, away, 0.01, "far from the band")
+	})
+	t.Run("preamp", func(t *testing.T) {
+		assert.InDelta(t, 0.125, amplitude(newEqualizerStreamer(sineStreamer(440), Equalizer{Preamp: -20 * math.Log10(2)}, sampleRate)), 0.001)
+	})
+	t.Run("change is crossfaded", func(t *testing.T) {
+		q := newEqualizerStreamer(sineStreamer(100), Equalizer{}, sampleRate)
+		samples := make([][2]float64, 1000)
+		q.Stream(samples)
+		last := samples[len(samples)-1][0]
+		q.set(Equalizer{Preamp: -12})
+		q.set(Equalizer{Preamp: -24}) // coalesced, faded in after the first
+		for range 10 {
+			q.Stream(samples)
+			for _, sample := range samples {
+				step := math.Abs(sample[0] - last)
+				assert.Less(t, step, 0.01, "no jump between samples")
+				last = sample[0]
+			}
+		}
+		assert.Nil(t, q.next)
+		assert.InDelta(t, 0.25*math.Pow(10, -24.0/20), amplitude(q), 0.001)
+	})
+	t.Run("flat again drop the filter state", func(t *testing.T) {
+		var eq Equalizer
+		eq.Gains[0] = 12
+		q := newEqualizerStreamer(sineStreamer(31), eq, sampleRate)
+		samples := make([][2]float64, 1000)
+		q.Stream(samples)
+		q.set(Equalizer{})
+		for range 5 {
+			q.Stream(samples)
+		}
+		assert.Nil(t, q.next)
+		for _, band := range q.current.bands {
+			assert.Equal(t, [2][2]float64{}, band.z)
+		}
+		last := samples[len(samples)-1][0]
+		q.set(eq)
+		for range 10 {
+			q.Stream(samples)
+			for _, sample := range samples {
+				assert.Less(t, math.Abs(sample[0]-last), 0.01, "no jump between samples")
+				last = sample[0]
+			}
+		}
+	})
+	t.Run("kept across tracks", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		e.SetEqualizer(Equalizer{Preamp: -6})
+		assert.NoError(t, e.Load([]Track{{Path: writeWAV(t, filepath.Join(t.TempDir(), "a.wav"), 44100, time.Second)}}, 0))
+		r.waitState(t, Paused)
+		assert.InDelta(t, math.Pow(10, -6.0/20), e.eqStreamer.current.gain, 1e-9, "a loaded track use the setting")
+		e.SetEqualizer(Equalizer{})
+		assert.NotNil(t, e.eqStreamer.next, "fading to the new setting")
+		assert.True(t, e.eqStreamer.flat)
+	})
+}
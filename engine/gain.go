@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"math"
+	"time"
+)
+
+// gainRamp is how long a change of gain take, so it does not click
+const gainRamp = 20 * time.Millisecond
+
+// GainFunc return the gain in dB to play the track at index of the queue with, such as its ReplayGain.
+// It is called from a goroutine of the engine, so it may read the file
+type GainFunc func(queue []Track, index int) float64
+
+// SetGain set how loud the tracks are played, the loaded track is changed too once fn returned
+func (e *Engine) SetGain(fn GainFunc) {
+	e.mu.Lock()
+	e.gain = fn
+	e.gains++
+	if e.streamer == nil {
+		e.mu.Unlock()
+		return
+	}
+	id, generation := e.gains, e.generation
+	queue, index := e.queue, e.index
+	if index == -1 { // no longer in the queue
+		queue, index = []Track{e.track}, 0
+	}
+	e.mu.Unlock()
+	go func() {
+		gain := trackGain(fn, queue, index)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if id != e.gains || generation != e.generation { // replaced meanwhile
+			return
+		}
+		e.trackGain = gain
+		e.out.Lock()
+		e.gainStreamer.set(gain)
+		e.out.Unlock()
+	}()
+}
+
+func trackGain(fn GainFunc, queue []Track, index int) float64 {
+	if fn == nil {
+		return 0
+	}
+	return fn(queue, index)
+}
+
+// gainStreamer amplify the stream, a new gain is ramped to over gainRamp
+type gainStreamer struct {
+	s      beep.Streamer
+	rate   beep.SampleRate
+	gain   float64 // linear
+	target float64
+	step   float64 // added to gain by sample until it reach target
+}
+
+func newGainStreamer(s beep.Streamer, db float64, rate beep.SampleRate) *gainStreamer {
+	gain := math.Pow(10, db/20)
+	return &gainStreamer{s: s, rate: rate, gain: gain, target: gain}
+}
+
+// set is called under the output lock
+func (g *gainStreamer) set(db float64) {
+	g.target = math.Pow(10, db/20)
+	g.step = (g.target - g.gain) / float64(g.rate.N(gainRamp))
+}
+
+func (g *gainStreamer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := g.s.Stream(samples)
+	if g.gain == 1 && g.target == 1 {
+		return n, ok
+	}
+	for i := range samples[:n] {
+		if g.gain != g.target {
+			g.gain += g.step
+			if (g.step > 0) == (g.gain > g.target) {
+				g.gain = g.target
+			}
+		}
+		samples[i][0] *= g.gain
+		samples[i][1] *= g.gain
+	}
+	return n, ok
+}
+
+func (g *gainStreamer) Err() error {
+	return g.s.Err()
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGain(t *testing.T) {
+	t.Run("gain", func(t *testing.T) {
+		assert.InDelta(t, 0.125, amplitude(newGainStreamer(sineStreamer(440), -20*math.Log10(2), sampleRate)), 0.001)
+	})
+	t.Run("change is ramped", func(t *testing.T) {
+		g := newGainStreamer(sineStreamer(100), 0, sampleRate)
+		samples := make([][2]float64, 1000)
+		g.Stream(samples)
+		last := samples[len(samples)-1][0]
+		g.set(-24)
+		for range 5 {
+			g.Stream(samples)
+			for _, sample := range samples {
+				assert.Less(t, math.Abs(sample[0]-last), 0.01, "no jump between samples")
+				last = sample[0]
+			}
+		}
+		assert.Equal(t, g.target, g.gain)
+		assert.InDelta(t, math.Pow(10, -24.0/20), g.gain, 1e-9)
+	})
+	t.Run("by track", func(t *testing.T) {
+		dir := t.TempDir()
+		tracks := []Track{
+			{Path: writeWAV(t, filepath.Join(dir, "a.wav"), 44100, time.Second)},
+			{Path: writeWAV(t, filepath.Join(dir, "b.wav"), 44100, time.Second)},
+		}
+		e := NewWithOutput(&fakeOutput{})
+		defer e.Close()
+		r := record(e)
+		e.SetGain(func(queue []Track, index int) float64 {
+			assert.Equal(t, tracks, queue)
+			return float64(-index)
+		})
+		assert.NoError(t, e.Load(tracks, 1))
+		r.waitState(t, Paused)
+		e.mu.Lock()
+		assert.Equal(t, -1.0, e.trackGain)
+		e.mu.Unlock()
+
+		changed := make(chan bool)
+		e.SetGain(func(queue []Track, index int) float64 {
+			defer close(changed)
+			return -6
+		})
+		<-changed
+		assert.Eventually(t, func() bool {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			return e.trackGain == -6
+		}, time.Second, time.Millisecond, "the loaded track is changed too")
+	})
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/speaker"
+	"time"
+)
+
+// Output is where the engine send the samples, it pulls them from the played streamers while not locked
+type Output interface {
+	Init(rate beep.SampleRate) error // called once before the first play
+	Play(s beep.Streamer)
+	Clear()
+	Lock()
+	Unlock()
+	Close() error
+}
+
+// Speaker play through the sound device with beep speaker, which is global so only one engine should use it
+type Speaker struct{}
+
+func (Speaker) Init(rate beep.SampleRate) error {
+	return speaker.Init(rate, rate.N(time.Second/10))
+}
+
+func (Speaker) Play(s beep.Streamer) { speaker.Play(s) }
+func (Speaker) Clear()               { speaker.Clear() }
+func (Speaker) Lock()                { speaker.Lock() }
+func (Speaker) Unlock()              { speaker.Unlock() }
+
+func (Speaker) Close() error {
+	speaker.Close()
+	return nil
+}
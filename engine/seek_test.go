@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSeek(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []Track{
+		{Path: writeWAV(t, filepath.Join(dir, "a.wav"), 44100, 3*time.Second)},
+		{Path: writeWAV(t, filepath.Join(dir, "b.wav"), 44100, 3*time.Second)},
+	}
+
+	t.Run("coalesced requests", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		r.waitState(t, Paused)
+		out.Lock() // the seeker wait for the output, so the requests pile up
+		var last uint64
+		for i := range 100 {
+			id := e.RequestSeek(time.Duration(i) * 10 * time.Millisecond)
+			assert.Greater(t, id, last)
+			last = id
+		}
+		out.Unlock()
+		var seeked []Event
+		for {
+			event := r.wait(t, Seeked)
+			seeked = append(seeked, event)
+			if event.Seek == last {
+				break
+			}
+		}
+		assert.Less(t, len(seeked), 100, "dropped the superseded requests")
+		assert.Equal(t, 990*time.Millisecond, seeked[len(seeked)-1].Position)
+		assert.Equal(t, 990*time.Millisecond, e.Position())
+	})
+	t.Run("sync seek supersede a pending request", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		r.waitState(t, Paused)
+		e.mu.Lock() // keep the request pending
+		e.seeks++
+		e.pendingSeek = &seekRequest{e.seeks, time.Second}
+		e.mu.Unlock()
+		assert.NoError(t, e.Seek(2*time.Second))
+		e.seekWake <- struct{}{}
+		assert.Equal(t, 2*time.Second, r.wait(t, Seeked).Position)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, 2*time.Second, e.Position(), "older request is not applied after")
+	})
+	t.Run("ticks tell the applied seek", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		id := e.RequestSeek(2 * time.Second)
+		assert.Equal(t, id, r.wait(t, Seeked).Seek)
+		tick := r.wait(t, Ticked)
+		assert.Equal(t, id, tick.Seek)
+		assert.Equal(t, 2*time.Second, tick.Position)
+	})
+	t.Run("request without track", func(t *testing.T) {
+		e := NewWithOutput(&fakeOutput{})
+		defer e.Close()
+		r := record(e)
+		id := e.RequestSeek(time.Second)
+		seeked := r.wait(t, Seeked)
+		assert.Equal(t, id, seeked.Seek)
+		assert.ErrorIs(t, seeked.Err, ErrNoTrack)
+	})
+	t.Run("concurrent use", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		assert.NoError(t, e.Load(tracks, 0))
+		assert.Equal(t, Paused, e.State())
+		var wg sync.WaitGroup
+		run := func(f func(i int)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 200 {
+					f(i)
+				}
+			}()
+		}
+		run(func(i int) { e.RequestSeek(time.Duration(i) * time.Millisecond) })
+		run(func(int) { e.Seek(time.Second) })
+		run(func(int) { e.Position() })
+		run(func(int) { out.pull(time.Millisecond) })
+		run(func(i int) {
+			if i%2 == 0 {
+				e.Play()
+			} else {
+				e.Pause()
+			}
+		})
+		run(func(i int) {
+			if i%50 == 0 {
+				e.Next()
+				e.Prev()
+			}
+		})
+		finished := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(finished)
+		}()
+		select {
+		case <-finished:
+		case <-time.After(10 * time.Second):
+			t.Fatal("deadlocked")
+		}
+		assert.NoError(t, e.Close())
+	})
+}
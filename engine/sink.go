@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"encoding/binary"
+	"github.com/gopxl/beep"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// sinkInterval is how often a sink pull the samples
+const sinkInterval = 10 * time.Millisecond
+
+// Sink is an output without sound device, it pulls the samples itself at speed times real time and hands them to write.
+// Nothing is pulled while nothing is played, so a sink only record what the engine play
+type Sink struct {
+	mu    sync.Mutex
+	mixer beep.Mixer
+	speed float64
+	begin func(rate beep.SampleRate) error // called by Init
+	write func(samples [][2]float64) error
+	end   func() error // called once the sink stopped pulling
+	stop  chan struct{}
+	done  chan error
+}
+
+// NewNullSink discard the samples, speed 1 is real time and 10 is ten times faster
+func NewNullSink(speed float64) *Sink {
+	return &Sink{speed: speed}
+}
+
+// NewWAVSink write the samples to a 16 bit stereo wav file at path
+func NewWAVSink(path string, speed float64) (*Sink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &wavWriter{w: f}
+	return &Sink{
+		speed: speed,
+		begin: w.header,
+		write: w.write,
+		end: func() error {
+			err := w.finish()
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		},
+	}, nil
+}
+
+func (s *Sink) Init(rate beep.SampleRate) error {
+	if s.begin != nil {
+		if err := s.begin(rate); err != nil {
+			return err
+		}
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan error, 1)
+	go s.pull(rate)
+	return nil
+}
+
+func (s *Sink) Play(streamer beep.Streamer) {
+	s.Lock()
+	s.mixer.Add(streamer)
+	s.Unlock()
+}
+
+func (s *Sink) Clear() {
+	s.Lock()
+	s.mixer.Clear()
+	s.Unlock()
+}
+
+func (s *Sink) Lock()   { s.mu.Lock() }
+func (s *Sink) Unlock() { s.mu.Unlock() }
+
+// Close stop pulling, the wav file is complete once it returns
+func (s *Sink) Close() error {
+	var err error
+	if s.stop != nil {
+		close(s.stop)
+		err = <-s.done
+	}
+	if s.end != nil {
+		if endErr := s.end(); err == nil {
+			err = endErr
+		}
+	}
+	return err
+}
+
+func (s *Sink) pull(rate beep.SampleRate) {
+	ticker := time.NewTicker(sinkInterval)
+	defer ticker.Stop()
+	samples := make([][2]float64, rate.N(time.Duration(float64(sinkInterval)*max(s.speed, 0.01))))
+	for {
+		select {
+		case <-s.stop:
+			s.done <- nil
+			return
+		case <-ticker.C:
+		}
+		s.Lock()
+		playing := s.mixer.Len() > 0
+		if playing {
+			s.mixer.Stream(samples)
+		}
+		s.Unlock()
+		if playing && s.write != nil {
+			if err := s.write(samples); err != nil {
+				s.done <- err
+				<-s.stop
+				return
+			}
+		}
+	}
+}
+
+// wavWriter write a wav whose sizes are only known at the end, they are filled in by finish
+type wavWriter struct {
+	w       io.WriteSeeker
+	started bool // whether the header is written
+	frames  int
+	buf     []byte
+}
+
+func (w *wavWriter) header(rate beep.SampleRate) error {
+	const channels, bytesPerSample = 2, 2
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // pcm
+	binary.LittleEndian.PutUint16(header[22:], channels)
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(rate)*channels*bytesPerSample)
+	binary.LittleEndian.PutUint16(header[32:], channels*bytesPerSample)
+	binary.LittleEndian.PutUint16(header[34:], bytesPerSample*8)
+	copy(header[36:], "data")
+	w.started = true
+	_, err := w.w.Write(header)
+	return err
+}
+
+func (w *wavWriter) write(samples [][2]float64) error {
+	w.buf = w.buf[:0]
+	for _, sample := range samples {
+		for _, value := range sample {
+			value = math.Max(-1, math.Min(1, value))
+			w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(int16(value*math.MaxInt16)))
+		}
+	}
+	w.frames += len(samples)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// finish fill in the sizes, a sink never played still make a valid empty wav
+func (w *wavWriter) finish() error {
+	if !w.started {
+		if err := w.header(sampleRate); err != nil {
+			return err
+		}
+	}
+	size := uint32(w.frames * 4)
+	sizes := []struct {
+		offset int64
+		value  uint32
+	}{{4, 36 + size}, {40, size}}
+	for _, field := range sizes {
+		if _, err := w.w.Seek(field.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if err := binary.Write(w.w, binary.LittleEndian, field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/gopxl/beep/wav"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSink(t *testing.T) {
+	dir := t.TempDir()
+	tracks := []Track{
+		{Path: writeWAV(t, filepath.Join(dir, "a.wav"), 44100, time.Second)},
+		{Path: writeWAV(t, filepath.Join(dir, "b.wav"), 22050, time.Second)},
+	}
+
+	t.Run("null sink", func(t *testing.T) {
+		e := NewWithOutput(NewNullSink(20))
+		r := record(e)
+		assert.NoError(t, e.Load(tracks[:1], 0))
+		started := time.Now()
+		assert.NoError(t, e.Play())
+		left := r.wait(t, TrackLeft)
+		assert.Equal(t, time.Second, left.Position)
+		r.waitState(t, Ended)
+		assert.Less(t, time.Since(started), time.Second/2, "faster than real time")
+		assert.NoError(t, e.Close())
+	})
+	t.Run("wav sink", func(t *testing.T) {
+		path := filepath.Join(dir, "out.wav")
+		sink, err := NewWAVSink(path, 20)
+		assert.NoError(t, err)
+		e := NewWithOutput(sink)
+		r := record(e)
+		assert.NoError(t, e.Load(tracks, 0))
+		assert.Equal(t, 0, r.wait(t, TrackChanged).Index)
+		assert.NoError(t, e.Play())
+		assert.Equal(t, 1, r.wait(t, TrackChanged).Index)
+		assert.Equal(t, 0, r.wait(t, TrackChanged).Index, "wrapped around")
+		assert.NoError(t, e.Close())
+
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		streamer, format, err := wav.Decode(f)
+		assert.NoError(t, err)
+		assert.Equal(t, sampleRate, format.SampleRate)
+		assert.Equal(t, 2, format.NumChannels)
+		assert.GreaterOrEqual(t, format.SampleRate.D(streamer.Len()), 2*time.Second)
+	})
+	t.Run("wav writer", func(t *testing.T) {
+		path := filepath.Join(dir, "writer.wav")
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		w := &wavWriter{w: f}
+		assert.NoError(t, w.header(22050))
+		assert.NoError(t, w.write([][2]float64{{0.5, -0.5}, {2, -2}}))
+		assert.NoError(t, w.finish())
+		f.Close()
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Len(t, data, 44+8)
+		assert.Equal(t, "RIFF", string(data[0:4]))
+		assert.Equal(t, uint32(36+8), binary.LittleEndian.Uint32(data[4:]))
+		assert.Equal(t, uint32(22050), binary.LittleEndian.Uint32(data[24:]))
+		assert.Equal(t, uint32(8), binary.LittleEndian.Uint32(data[40:]))
+		var pcm [4]int16
+		binary.Read(bytes.NewReader(data[44:]), binary.LittleEndian, &pcm)
+		assert.Equal(t, [4]int16{16383, -16383, 32767, -32767}, pcm, "clipped to full scale")
+
+		f, err = os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		streamer, format, err := wav.Decode(f)
+		assert.NoError(t, err)
+		assert.Equal(t, 22050, int(format.SampleRate))
+		assert.Equal(t, 2, streamer.Len())
+	})
+	t.Run("unplayed wav sink", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.wav")
+		sink, err := NewWAVSink(path, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Close())
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		streamer, _, err := wav.Decode(f)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, streamer.Len())
+	})
+}
@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"math"
+	"time"
+)
+
+// SpeedMode is how the playback speed is changed
+type SpeedMode int
+
+const (
+	KeepPitch   SpeedMode = iota // time stretched, voices keep their pitch
+	ChangePitch                  // resampled, faster is higher like a tape
+)
+
+const (
+	MinSpeed = 0.5
+	MaxSpeed = 3.0
+)
+
+const (
+	stretchFrame     = 40 * time.Millisecond // length of the overlapped frames of the time stretch
+	stretchTolerance = 10 * time.Millisecond // how far a frame may move from where it is due to continue the previous one
+)
+
+// SetSpeed change how fast the tracks are played, clamped to MinSpeed and MaxSpeed. The position stay in media time
+func (e *Engine) SetSpeed(speed float64, mode SpeedMode) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.speed, e.speedMode = min(max(speed, MinSpeed), MaxSpeed), mode
+	if e.speedStreamer != nil {
+		e.out.Lock()
+		e.speedStreamer.set(e.speed, mode)
+		e.out.Unlock()
+	}
+}
+
+// Speed of the playback and how it is changed
+func (e *Engine) Speed() (float64, SpeedMode) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.speed, e.speedMode
+}
+
+// speedStreamer play the source faster or slower, by WSOLA (waveform similarity overlap-add) to keep the pitch or by resampling.
+// It read ahead of what it played, so position is where the played samples are in the source
+type speedStreamer struct {
+	s         beep.StreamSeeker
+	speed     float64
+	mode      SpeedMode
+	position  float64 // in the source of the next sample played
+	resampler *beep.Resampler
+	frame     int // samples of a frame, overlapping the previous one by half
+	hop       int
+	tolerance int
+	window    []float64
+	in        [][2]float64 // source read ahead, in[0] is at inStart
+	inStart   int
+	due       float64 // where the next frame is due in the source
+	next      int     // where the source continue the previous frame, -1 before the first frame
+	out       [][2]float64
+	tail      [][2]float64 // second half of the previous frame, added to the first half of the next one
+	drained   bool
+	// reused by every hop, so the audio thread does not allocate
+	outBuffer  [][2]float64
+	tailBuffer [][2]float64
+	candidates []float64
+	target     []float64
+	read       [][2]float64
+	inBuffer   [][2]float64 // backing of in
+}
+
+func newSpeedStreamer(s beep.StreamSeeker, rate beep.SampleRate, speed float64, mode SpeedMode) *speedStreamer {
+	st := &speedStreamer{s: s, speed: speed, mode: mode, frame: rate.N(stretchFrame) &^ 1, tolerance: rate.N(stretchTolerance)}
+	st.hop = st.frame / 2
+	st.outBuffer, st.tailBuffer = make([][2]float64, st.hop), make([][2]float64, st.hop)
+	st.candidates, st.target = make([]float64, 2*st.tolerance+st.hop), make([]float64, st.hop)
+	st.read = make([][2]float64, 512)
+	st.inBuffer = make([][2]float64, 2*st.frame+2*st.tolerance+2*len(st.read))
+	st.window = make([]float64, st.frame)
+	for i := range st.window { // periodic hann, the halves of overlapped frames sum to one
+		st.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(st.frame))
+	}
+	st.reset(s.Position())
+	return st
+}
+
+// reset drop what is read ahead once the source is seeked to position, called under the output lock
+func (st *speedStreamer) reset(position int) {
+	st.position, st.due, st.next = float64(position), float64(position), -1
+	st.in, st.inStart, st.out, st.tail, st.drained = st.inBuffer[:0], position, nil, nil, false
+	st.resampler = nil
+	if st.mode == ChangePitch && st.speed != 1 {
+		st.resampler = beep.ResampleRatio(4, st.speed, st.s)
+	}
+}
+
+// set is called under the output lock, the source is seeked back to what is played so the change does not skip
+func (st *speedStreamer) set(speed float64, mode SpeedMode) {
+	if speed == st.speed && mode == st.mode {
+		return
+	}
+	if st.resampler != nil && mode == ChangePitch && speed != 1 {
+		st.speed = speed
+		st.resampler.SetRatio(speed)
+		return
+	}
+	st.speed, st.mode = speed, mode
+	position := min(int(math.Round(st.position)), st.s.Len())
+	if position != st.s.Position() {
+		if err := st.s.Seek(position); err != nil {
+			position = st.s.Position()
+		}
+	}
+	st.reset(position)
+}
+
+func (st *speedStreamer) Stream(samples [][2]float64) (int, bool) {
+	var n int
+	var ok bool
+	switch {
+	case st.speed == 1:
+		n, ok = st.s.Stream(samples)
+	case st.resampler != nil:
+		n, ok = st.resampler.Stream(samples)
+	default:
+		for n < len(samples) && (len(st.out) > 0 || st.stretch()) {
+			copied := copy(samples[n:], st.out)
+			st.out = st.out[copied:]
+			n += copied
+		}
+		ok = n > 0
+	}
+	st.position += float64(n) * st.speed
+	return n, ok
+}
+
+func (st *speedStreamer) Err() error {
+	return st.s.Err()
+}
+
+// stretch overlap-add the next frame, the one most like how the source continue the previous frame near where it is due.
+// False once the source is drained
+func (st *speedStreamer) stretch() bool {
+	due := int(math.Round(st.due))
+	from, to := max(due-st.tolerance, st.inStart), due+st.tolerance
+	if st.next == -1 {
+		from, to = max(due, st.inStart), max(due, st.inStart)
+	}
+	st.fill(to + st.frame)
+	end := st.inStart + len(st.in)
+	if due >= end {
+		st.out, st.tail = st.tail, nil
+		return len(st.out) > 0
+	}
+	to = min(to, end-1)
+	best := from
+	if st.next != -1 {
+		candidates, target := st.mono(st.candidates[:to-from+st.hop], from), st.mono(st.target, st.next)
+		bestScore := math.Inf(-1)
+		for start := from; start <= to; start++ {
+			var correlation, energy float64
+			candidate := candidates[start-from:]
+			for i := 0; i < st.hop; i += 2 { // every other sample is close enough, and half the work
+				correlation += candidate[i] * target[i]
+				energy += candidate[i] * candidate[i]
+			}
+			if score := correlation / math.Sqrt(energy+1e-9); score > bestScore {
+				best, bestScore = start, score
+			}
+		}
+	}
+	// the previous tail is read by the first half before the second half overwrite it
+	st.out = st.outBuffer
+	tail := st.tailBuffer
+	for i := range st.frame {
+		sample := st.sample(best + i)
+		for c := range 2 {
+			if i < st.hop {
+				st.out[i][c] = sample[c] * st.window[i]
+				if st.tail != nil {
+					st.out[i][c] += st.tail[i][c]
+				}
+			} else {
+				tail[i-st.hop][c] = sample[c] * st.window[i]
+			}
+		}
+	}
+	st.tail, st.next = tail, best+st.hop
+	st.due += float64(st.hop) * st.speed
+	if keep := min(int(st.due)-st.tolerance, st.next) - st.inStart; keep > 0 {
+		keep = min(keep, len(st.in))
+		st.in, st.inStart = st.in[keep:], st.inStart+keep
+	}
+	return true
+}
+
+// fill read the source until end, or until it is drained. What is left of the read ahead is moved back to the start of its buffer
+// instead of growing it
+func (st *speedStreamer) fill(end int) {
+	for !st.drained && st.inStart+len(st.in) < end {
+		if cap(st.in)-len(st.in) < len(st.read) {
+			if len(st.in)+len(st.read) > len(st.inBuffer) {
+				st.inBuffer = make([][2]float64, 2*(len(st.in)+len(st.read)))
+			}
+			st.in = st.inBuffer[:copy(st.inBuffer, st.in)]
+		}
+		n, ok := st.s.Stream(st.read)
+		st.in = append(st.in, st.read[:n]...)
+		st.drained = !ok
+	}
+}
+
+// sample of the source at position, silence past its end
+func (st *speedStreamer) sample(position int) [2]float64 {
+	if i := position - st.inStart; i >= 0 && i < len(st.in) {
+		return st.in[i]
+	}
+	return [2]float64{}
+}
+
+// mono mix the samples of the source from position into mixed, what frames are compared by
+func (st *speedStreamer) mono(mixed []float64, position int) []float64 {
+	for i := range mixed {
+		sample := st.sample(position + i)
+		mixed[i] = sample[0] + sample[1]
+	}
+	return mixed
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sineSource is a seekable sine of the duration
+func sineSource(frequency float64, d time.Duration) beep.StreamSeeker {
+	buffer := beep.NewBuffer(beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2})
+	buffer.Append(beep.Take(sampleRate.N(d), sineStreamer(frequency)))
+	return buffer.Streamer(0, buffer.Len())
+}
+
+// drain stream s to its end
+func drain(s beep.Streamer) [][2]float64 {
+	var all [][2]float64
+	samples := make([][2]float64, 1000)
+	for {
+		n, ok := s.Stream(samples)
+		all = append(all, samples[:n]...)
+		if !ok {
+			return all
+		}
+	}
+}
+
+// frequency count the rising zero crossings of the middle of the samples
+func frequency(samples [][2]float64) float64 {
+	middle := samples[len(samples)/4 : len(samples)*3/4]
+	var crossings int
+	for i := 1; i < len(middle); i++ {
+		if middle[i-1][0] < 0 && middle[i][0] >= 0 {
+			crossings++
+		}
+	}
+	return float64(crossings) / sampleRate.D(len(middle)).Seconds()
+}
+
+func TestSpeed(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		speed     float64
+		mode      SpeedMode
+		frequency float64
+	}{
+		{"faster keeping pitch", 2, KeepPitch, 440},
+		{"slower keeping pitch", 0.5, KeepPitch, 440},
+		{"faster changing pitch", 2, ChangePitch, 880},
+		{"slower changing pitch", 0.5, ChangePitch, 220},
+		{"normal", 1, KeepPitch, 440},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			source := sineSource(440, 2*time.Second)
+			s := newSpeedStreamer(source, sampleRate, c.speed, c.mode)
+			samples := drain(s)
+			assert.InDelta(t, 2/c.speed, sampleRate.D(len(samples)).Seconds(), 0.05)
+			assert.InDelta(t, c.frequency, frequency(samples), c.frequency*0.02)
+			assert.InDelta(t, source.Len(), s.position, float64(sampleRate.N(50*time.Millisecond)), "media time")
+		})
+	}
+	t.Run("changed while playing", func(t *testing.T) {
+		source := sineSource(440, 2*time.Second)
+		s := newSpeedStreamer(source, sampleRate, 1.5, KeepPitch)
+		s.Stream(make([][2]float64, 10000))
+		assert.Greater(t, source.Position(), int(s.position), "read ahead")
+		s.set(1, KeepPitch)
+		assert.Equal(t, 15000, source.Position(), "seeked back to what is played")
+		s.set(2, ChangePitch)
+		s.Stream(make([][2]float64, 1000))
+		s.set(3, ChangePitch)
+		assert.NotNil(t, s.resampler, "ratio changed in place")
+		assert.Equal(t, 3.0, s.speed)
+	})
+	t.Run("engine position in media time", func(t *testing.T) {
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		e.SetSpeed(10, KeepPitch)
+		speed, _ := e.Speed()
+		assert.Equal(t, MaxSpeed, speed, "clamped")
+		e.SetSpeed(2, KeepPitch)
+		assert.NoError(t, e.Load([]Track{{Path: writeWAV(t, filepath.Join(t.TempDir(), "a.wav"), 44100, 3*time.Second)}}, 0))
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		out.pull(time.Second / 2)
+		assert.InDelta(t, time.Second, e.Position(), float64(20*time.Millisecond))
+		assert.Equal(t, 3*time.Second, e.Duration())
+		assert.NoError(t, e.Seek(2*time.Second))
+		out.pull(time.Second / 4)
+		assert.InDelta(t, 2500*time.Millisecond, e.Position(), float64(20*time.Millisecond), "seek drop what is read ahead")
+	})
+}
+
+// BenchmarkSpeed report how long stretching take relative to the audio played, which must stay well under 1
+func BenchmarkSpeed(b *testing.B) {
+	const length = 10 * time.Second
+	source := sineSource(440, length)
+	samples := make([][2]float64, 512)
+	b.ReportAllocs()
+	for b.Loop() {
+		source.Seek(0)
+		s := newSpeedStreamer(source, sampleRate, MaxSpeed, KeepPitch)
+		for {
+			if _, ok := s.Stream(samples); !ok {
+				break
+			}
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed())/float64(b.N)/float64(length/MaxSpeed), "realtime")
+}
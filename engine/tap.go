@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"sync"
+)
+
+// tapSize is how many of the latest samples a tap keep, enough for a 4096 point FFT
+const tapSize = 4096
+
+// Tap keep the latest samples sent to the output, for a visualisation. The output rate is always sampleRate.
+// The audio never wait for a reader: the samples arriving while Latest copies are dropped from the tap instead
+type Tap struct {
+	mu   sync.Mutex
+	ring [tapSize][2]float64
+	next int // index of the ring written next
+}
+
+// SampleRate of the tapped samples
+func (t *Tap) SampleRate() beep.SampleRate {
+	return sampleRate
+}
+
+// Latest copy the latest len(samples) samples played into samples, oldest first, up to the size of the tap
+func (t *Tap) Latest(samples [][2]float64) {
+	n := min(len(samples), tapSize)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := (t.next - n + tapSize) % tapSize
+	copied := copy(samples[:n], t.ring[start:])
+	copy(samples[copied:n], t.ring[:n-copied])
+}
+
+// write is called by the output, it returns right away when a reader hold the lock
+func (t *Tap) write(samples [][2]float64) {
+	if !t.mu.TryLock() {
+		return
+	}
+	defer t.mu.Unlock()
+	if len(samples) > tapSize {
+		samples = samples[len(samples)-tapSize:]
+	}
+	for len(samples) > 0 {
+		copied := copy(t.ring[t.next:], samples)
+		t.next = (t.next + copied) % tapSize
+		samples = samples[copied:]
+	}
+}
+
+// clear the tap once nothing is played, so it is silent rather than stuck on the last samples
+func (t *Tap) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ring = [tapSize][2]float64{}
+}
+
+// tapStreamer stream s, handing a copy of the samples to the tap
+type tapStreamer struct {
+	s   beep.Streamer
+	tap *Tap
+}
+
+func (s *tapStreamer) Stream(samples [][2]float64) (int, bool) {
+	n, ok := s.s.Stream(samples)
+	s.tap.write(samples[:n])
+	return n, ok
+}
+
+func (s *tapStreamer) Err() error {
+	return s.s.Err()
+}
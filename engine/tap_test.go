@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/wav"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTap(t *testing.T) {
+	t.Run("latest samples oldest first", func(t *testing.T) {
+		var tap Tap
+		samples := make([][2]float64, tapSize+100)
+		for i := range samples {
+			samples[i] = [2]float64{float64(i), -float64(i)}
+		}
+		tap.write(samples[:tapSize-10])
+		tap.write(samples[tapSize-10:]) // wrap around the ring
+		latest := make([][2]float64, 20)
+		tap.Latest(latest)
+		assert.Equal(t, samples[len(samples)-20:], latest)
+	})
+	t.Run("more than the tap keep", func(t *testing.T) {
+		var tap Tap
+		samples := make([][2]float64, 2*tapSize)
+		for i := range samples {
+			samples[i] = [2]float64{float64(i), 0}
+		}
+		tap.write(samples)
+		latest := make([][2]float64, tapSize)
+		tap.Latest(latest)
+		assert.Equal(t, samples[tapSize:], latest)
+	})
+	t.Run("writing never wait for a reader", func(t *testing.T) {
+		var tap Tap
+		tap.mu.Lock()
+		tap.write([][2]float64{{1, 1}})
+		tap.mu.Unlock()
+		latest := make([][2]float64, 1)
+		tap.Latest(latest)
+		assert.Equal(t, [2]float64{}, latest[0], "dropped")
+	})
+	t.Run("fed by the engine after the pause control", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tone.wav")
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		tone := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+			for i := range samples {
+				samples[i] = [2]float64{0.5, 0.5}
+			}
+			return len(samples), true
+		})
+		assert.NoError(t, wav.Encode(f, beep.Take(sampleRate.N(time.Second), tone), beep.Format{SampleRate: sampleRate, NumChannels: 2, Precision: 2}))
+		f.Close()
+
+		out := &fakeOutput{}
+		e := NewWithOutput(out)
+		defer e.Close()
+		r := record(e)
+		latest := make([][2]float64, 100)
+		assert.NoError(t, e.Load([]Track{{Path: path}}, 0))
+		assert.NoError(t, e.Play())
+		r.waitState(t, Playing)
+		out.pull(100 * time.Millisecond)
+		e.Tap().Latest(latest)
+		assert.Greater(t, latest[99][0], 0.1)
+
+		e.Pause()
+		r.waitState(t, Paused)
+		out.pull(100 * time.Millisecond)
+		e.Tap().Latest(latest)
+		assert.Equal(t, [2]float64{}, latest[99], "paused is silent")
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
-	"github.com/gopxl/beep/speaker"
 	"github.com/gorgemul/musicplayer/player"
 )
 
@@ -14,20 +13,46 @@ func main() {
 	app := app.New()
 	window := app.NewWindow("music player")
 	p := player.New(window)
-	window.SetContent(container.NewBorder(nil, nil, nil, renderPlayer(p), renderPlaylist(p.Playlist)))
+	window.SetContent(container.NewBorder(nil, nil, nil, renderPlayer(p), renderPlaylist(p)))
 	window.ShowAndRun()
-	speaker.Clear()
-	speaker.Close()
+	p.Close()
 }
 
-func renderPlaylist(pl *player.Playlist) fyne.CanvasObject {
-	return container.NewBorder(
-		container.NewVBox(container.NewHBox(layout.NewSpacer(), pl.UI.ImportFromFileBtn, pl.UI.ImportFromDirBtn, layout.NewSpacer()), widget.NewSeparator()),
+func renderPlaylist(p *player.Player) fyne.CanvasObject {
+	savedUI := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle("Saved playlists", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			container.NewHBox(layout.NewSpacer(), p.UI.SmartPlaylistBtn, layout.NewSpacer()),
+			widget.NewSeparator(),
+		),
+		nil,
+		nil,
+		nil,
+		p.UI.SavedPlaylists,
+	)
+	libraryUI := container.NewBorder(
+		container.NewVBox(container.NewHBox(layout.NewSpacer(), p.UI.LibraryAddBtn, p.UI.LibraryFoldersBtn, p.UI.LibraryRescanBtn, p.UI.LibraryLoudnessBtn, layout.NewSpacer()), widget.NewSeparator()),
+		container.NewVBox(widget.NewSeparator(), p.UI.LibraryStatus),
+		nil,
 		nil,
+		p.UI.Library,
+	)
+	historyUI := container.NewBorder(
+		container.NewVBox(container.NewHBox(layout.NewSpacer(), p.UI.HistoryReportBtn, p.UI.HistoryExportBtn, p.UI.HistoryThresholdBtn, layout.NewSpacer()), widget.NewSeparator()),
 		nil,
 		nil,
-		pl.UI.List,
+		nil,
+		p.UI.History,
+	)
+	browserUI := container.NewAppTabs(
+		container.NewTabItem("Library", libraryUI),
+		container.NewTabItem("Albums", container.NewBorder(container.NewVBox(p.UI.AlbumGridMode, widget.NewSeparator()), nil, nil, nil, p.UI.AlbumGrid)),
+		container.NewTabItem("Playlists", savedUI),
+		container.NewTabItem("History", historyUI),
 	)
+	split := container.NewHSplit(browserUI, p.UI.Playlists)
+	split.Offset = 0.3
+	return split
 }
 
 func renderPlayer(p *player.Player) fyne.CanvasObject {
@@ -36,8 +61,10 @@ func renderPlayer(p *player.Player) fyne.CanvasObject {
 		container.NewHBox(layout.NewSpacer(), container.NewVBox(p.UI.AlbumTitle, p.UI.AlbumArtist), layout.NewSpacer()),
 	)
 	controlGroupUI := container.NewVBox(
-		container.NewHBox(layout.NewSpacer(), p.UI.PrevBtn, p.UI.PlayBtn, p.UI.NextBtn, layout.NewSpacer()),
+		container.NewHBox(layout.NewSpacer(), p.UI.PrevBtn, p.UI.PlayBtn, p.UI.NextBtn, p.UI.VisualizerBtn, p.UI.EqualizerBtn, p.UI.ReplayGainBtn, p.UI.SpeedBtn, layout.NewSpacer()),
 		layout.NewSpacer(),
+		p.UI.Visualizer,
+		p.UI.Waveform,
 		container.NewBorder(nil, nil, p.UI.ProgressLabel, p.UI.DurationLabel, p.UI.Slider),
 	)
 	return container.NewHBox(
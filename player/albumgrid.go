@@ -0,0 +1,110 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"slices"
+)
+
+const (
+	appendAlbum  = "Append to playlist"
+	replaceAlbum = "Replace playlist"
+)
+
+type gridAlbum struct {
+	artist string
+	libraryAlbum
+}
+
+// gridAlbums list every album of the artists, its tracks are already in disc and track order
+func gridAlbums(artists []libraryArtist) []gridAlbum {
+	var albums []gridAlbum
+	for _, artist := range artists {
+		for _, album := range artist.albums {
+			albums = append(albums, gridAlbum{artist.name, album})
+		}
+	}
+	return albums
+}
+
+type albumCard struct {
+	widget.BaseWidget
+	cover          *canvas.Image
+	title          *widget.Label
+	artist         *widget.Label
+	onDoubleTapped func()
+}
+
+func newAlbumCard() *albumCard {
+	c := &albumCard{
+		cover:  canvas.NewImageFromImage(nil),
+		title:  widget.NewLabelWithStyle("albumTitle", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		artist: widget.NewLabelWithStyle("albumArtist", fyne.TextAlignCenter, fyne.TextStyle{}),
+	}
+	c.cover.FillMode = canvas.ImageFillContain
+	c.cover.SetMinSize(fyne.NewSquareSize(thumbnailSize))
+	c.title.Truncation = fyne.TextTruncateEllipsis
+	c.artist.Truncation = fyne.TextTruncateEllipsis
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *albumCard) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewVBox(c.cover, c.title, c.artist))
+}
+
+func (c *albumCard) DoubleTapped(*fyne.PointEvent) {
+	if c.onDoubleTapped != nil {
+		c.onDoubleTapped()
+	}
+}
+
+func (c *albumCard) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
+
+func (p *Player) newAlbumGrid() {
+	p.UI.AlbumGridMode = widget.NewSelect([]string{appendAlbum, replaceAlbum}, nil)
+	p.UI.AlbumGridMode.SetSelected(appendAlbum)
+	p.UI.AlbumGrid = widget.NewGridWrap(
+		func() int {
+			return len(p.library.albums)
+		},
+		func() fyne.CanvasObject {
+			return newAlbumCard()
+		},
+		func(id widget.GridWrapItemID, o fyne.CanvasObject) {
+			album := p.library.albums[id]
+			c := o.(*albumCard)
+			c.cover.Image, _ = p.covers.get(album.tracks[0].Path)
+			c.cover.Refresh()
+			c.title.SetText(album.title)
+			c.artist.SetText(album.artist)
+			c.onDoubleTapped = func() {
+				p.openAlbum(album, p.UI.AlbumGridMode.Selected == replaceAlbum)
+			}
+		})
+}
+
+// openAlbum add the tracks of the album into the selected playlist, replacing its songs when replace
+func (p *Player) openAlbum(album gridAlbum, replace bool) {
+	index := slices.IndexFunc(p.playlists, func(pl *Playlist) bool {
+		return pl.tab == p.UI.Playlists.Selected()
+	})
+	if index == -1 {
+		return
+	}
+	pl := p.playlists[index]
+	var items []playlistItem
+	for _, track := range album.tracks {
+		items = append(items, playlistItem{path: track.Path, duration: -1})
+	}
+	if replace { // the songs are replaced once the album is imported, a smart playlist is refused as is
+		p.replaceItems(pl, "Open "+album.title, items)
+		return
+	}
+	p.importItems(pl, "Add "+album.title, items)
+}
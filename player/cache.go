@@ -0,0 +1,80 @@
+package player
+
+import (
+	"sync"
+	"time"
+)
+
+// lazyCache read the value of a file in background the first time it is asked for
+type lazyCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]T
+	queue   []string
+	queued  map[string]bool
+	loading bool
+	read    func(path string) T
+	loaded  func() // called from the loading goroutine after a batch is cached
+}
+
+func newLazyCache[T any](read func(path string) T, loaded func()) *lazyCache[T] {
+	return &lazyCache[T]{entries: map[string]T{}, queued: map[string]bool{}, read: read, loaded: loaded}
+}
+
+// get return the cached value, ok is false when it is still loading
+func (c *lazyCache[T]) get(path string) (value T, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.entries[path]; ok {
+		return value, true
+	}
+	if !c.queued[path] {
+		c.queued[path] = true
+		c.queue = append(c.queue, path)
+		if !c.loading {
+			c.loading = true
+			go c.load()
+		}
+	}
+	return value, false
+}
+
+// put cache the value already known, such as read by the library
+func (c *lazyCache[T]) put(path string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[path] = value
+}
+
+// forget drop the cached value, so it is read again next time
+func (c *lazyCache[T]) forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, path)
+}
+
+func (c *lazyCache[T]) load() {
+	lastNotify := time.Now()
+	for {
+		c.mu.Lock()
+		if len(c.queue) == 0 {
+			c.loading = false
+			c.mu.Unlock()
+			c.loaded()
+			return
+		}
+		path := c.queue[0]
+		c.queue = c.queue[1:]
+		c.mu.Unlock()
+		value := c.read(path)
+		c.mu.Lock()
+		if c.queued[path] {
+			c.entries[path] = value
+			delete(c.queued, path)
+		}
+		c.mu.Unlock()
+		if time.Since(lastNotify) > 200*time.Millisecond {
+			c.loaded()
+			lastNotify = time.Now()
+		}
+	}
+}
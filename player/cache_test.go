@@ -0,0 +1,23 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestLazyCache(t *testing.T) {
+	loaded := make(chan bool, 1)
+	cache := newLazyCache(func(string) int { return 1 }, func() { loaded <- true })
+	path := filepath.Join(t.TempDir(), "missing.wav")
+	_, ok := cache.get(path)
+	assert.False(t, ok)
+	<-loaded
+	value, ok := cache.get(path)
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	cache.forget(path)
+	_, ok = cache.get(path)
+	assert.False(t, ok)
+	<-loaded
+}
@@ -0,0 +1,95 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// barChart draw a vertical bar for every value, labelled below, with canvas objects only so it need nothing online
+type barChart struct {
+	widget.BaseWidget
+	labels []string
+	values []float64
+}
+
+func newBarChart(labels []string, values []float64) *barChart {
+	c := &barChart{labels: labels, values: values}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *barChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &barChartRenderer{chart: c}
+	for _, label := range c.labels {
+		bar := canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+		text := canvas.NewText(label, theme.Color(theme.ColorNameForeground))
+		text.TextSize = theme.CaptionTextSize()
+		text.Alignment = fyne.TextAlignCenter
+		r.bars = append(r.bars, bar)
+		r.texts = append(r.texts, text)
+	}
+	r.axis = canvas.NewLine(theme.Color(theme.ColorNameSeparator))
+	return r
+}
+
+type barChartRenderer struct {
+	chart *barChart
+	bars  []*canvas.Rectangle
+	texts []*canvas.Text
+	axis  *canvas.Line
+}
+
+func (r *barChartRenderer) Layout(size fyne.Size) {
+	if len(r.bars) == 0 {
+		return
+	}
+	var highest float64
+	for _, value := range r.chart.values {
+		highest = max(highest, value)
+	}
+	textHeight := fyne.MeasureText("0", theme.CaptionTextSize(), fyne.TextStyle{}).Height
+	chartHeight := size.Height - textHeight - theme.Padding()
+	slot := size.Width / float32(len(r.bars))
+	for i, bar := range r.bars {
+		height := float32(0)
+		if highest > 0 {
+			height = chartHeight * float32(r.chart.values[i]/highest)
+		}
+		bar.Move(fyne.NewPos(slot*float32(i)+slot*0.15, chartHeight-height))
+		bar.Resize(fyne.NewSize(slot*0.7, height))
+		r.texts[i].Move(fyne.NewPos(slot*float32(i), chartHeight+theme.Padding()))
+		r.texts[i].Resize(fyne.NewSize(slot, textHeight))
+	}
+	r.axis.Position1 = fyne.NewPos(0, chartHeight)
+	r.axis.Position2 = fyne.NewPos(size.Width, chartHeight)
+}
+
+func (r *barChartRenderer) MinSize() fyne.Size {
+	var width float32
+	for _, text := range r.texts {
+		width = max(width, text.MinSize().Width)
+	}
+	return fyne.NewSize((width+theme.Padding())*float32(len(r.texts)), 120)
+}
+
+func (r *barChartRenderer) Refresh() {
+	for i, bar := range r.bars {
+		bar.FillColor = theme.Color(theme.ColorNamePrimary)
+		r.texts[i].Color = theme.Color(theme.ColorNameForeground)
+	}
+	r.axis.StrokeColor = theme.Color(theme.ColorNameSeparator)
+	r.Layout(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *barChartRenderer) Objects() []fyne.CanvasObject {
+	objects := []fyne.CanvasObject{r.axis}
+	for i := range r.bars {
+		objects = append(objects, r.bars[i], r.texts[i])
+	}
+	return objects
+}
+
+func (r *barChartRenderer) Destroy() {}
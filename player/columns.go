@@ -0,0 +1,136 @@
+package player
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"time"
+)
+
+// fixed width of the column, 0 for the columns sharing the remaining width by columnWeights
+var (
+	columnWidths  = []float32{48, 0, 0, 0, 96, 64, 112, 64}
+	columnWeights = []float32{0, 3, 2, 2, 0, 0, 0, 0}
+)
+
+// columnLayout place the playlist header and entries in aligned columns
+type columnLayout struct{}
+
+func (columnLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	padding := theme.Padding()
+	remaining := size.Width - padding*float32(len(objects)-1)
+	var weights float32
+	for i := range objects {
+		remaining -= columnWidths[i]
+		weights += columnWeights[i]
+	}
+	remaining = max(remaining, 0)
+	var x float32
+	for i, object := range objects {
+		width := columnWidths[i]
+		if columnWeights[i] > 0 {
+			width = remaining * columnWeights[i] / weights
+		}
+		object.Move(fyne.NewPos(x, 0))
+		object.Resize(fyne.NewSize(width, size.Height))
+		x += width + padding
+	}
+}
+
+func (columnLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var size fyne.Size
+	for i, object := range objects {
+		min := object.MinSize()
+		size.Width += columnWidths[i]
+		if columnWeights[i] > 0 {
+			size.Width += min.Width
+		}
+		size.Height = max(size.Height, min.Height)
+	}
+	size.Width += theme.Padding() * float32(len(objects)-1)
+	return size
+}
+
+func (p *Player) songInfo(s song) metadata {
+	m, _ := p.metadata.get(s.path)
+	info := songInfo(s, m)
+	stats := p.stats[s.historyKey()]
+	info.plays, info.skips, info.lastPlayed = stats.plays, stats.skips, stats.lastPlayed
+	return info
+}
+
+func (p *Player) refreshPlaylist(pl *Playlist) {
+	if pl == p.Playlist {
+		p.syncQueue()
+	}
+	p.applyFilter(pl)
+	pl.UI.List.Refresh()
+	p.refreshFooter(pl)
+}
+
+func (p *Player) refreshFooter(pl *Playlist) {
+	var (
+		total   time.Duration
+		unknown int
+	)
+	for _, s := range pl.songs {
+		if duration := p.songInfo(s).duration; duration > 0 {
+			total += duration
+		} else {
+			unknown++
+		}
+	}
+	pl.UI.Footer.SetText(footerText(len(pl.songs), total, unknown))
+	for i, button := range pl.UI.Header {
+		switch {
+		case !pl.sorted || column(i) != pl.sortBy:
+			button.SetIcon(nil)
+		case pl.sortDescending:
+			button.SetIcon(theme.MenuDropDownIcon())
+		default:
+			button.SetIcon(theme.MenuDropUpIcon())
+		}
+	}
+}
+
+func footerText(count int, total time.Duration, unknown int) string {
+	text := fmt.Sprintf("%d tracks, %s", count, formatTime(total.Seconds()))
+	if count == 1 {
+		text = fmt.Sprintf("1 track, %s", formatTime(total.Seconds()))
+	}
+	if unknown > 0 {
+		text += fmt.Sprintf(" (%d without duration)", unknown)
+	}
+	return text
+}
+
+// sortPlaylist sort the playlist by the column, clicking the sorted column again reverse the order
+func (p *Player) sortPlaylist(pl *Playlist, by column) {
+	pl.sortDescending = pl.sorted && pl.sortBy == by && !pl.sortDescending
+	pl.sortBy = by
+	pl.sorted = true
+	var playing song
+	if pl.playingIndex != -1 {
+		playing = pl.songs[pl.playingIndex]
+	}
+	sortSongs(pl.songs, p.songInfo, by, pl.sortDescending)
+	for i, s := range pl.songs {
+		if pl.playingIndex != -1 && s.is(playing) {
+			pl.playingIndex = i
+			break
+		}
+	}
+	p.refreshPlaylist(pl)
+	p.updateSkipButtons()
+}
+
+// formatLastPlayed show only the time for today, empty when never played
+func formatLastPlayed(t, now time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case t.YearDay() == now.YearDay() && t.Year() == now.Year():
+		return t.Format("15:04")
+	}
+	return t.Format("2006-01-02")
+}
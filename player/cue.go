@@ -0,0 +1,204 @@
+package player
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type cueTrack struct {
+	file      string
+	title     string
+	performer string
+	number    int
+	start     time.Duration
+	indexed   bool
+}
+
+/*
+PERFORMER "Album Artist"
+TITLE "Album"
+FILE "album.flac" WAVE
+TRACK 01 AUDIO
+TITLE "Track"
+PERFORMER "Artist"
+INDEX 00 <mm:ss:ff>
+INDEX 01 <mm:ss:ff>
+
+every audio TRACK is expanded into a virtual track, which end at INDEX 01 of the next track in the same FILE
+*/
+func readCUE(r io.Reader, base string) ([]playlistItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) { // old rips are usually latin-1 encoded
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		data = []byte(string(runes))
+	}
+	var (
+		albumTitle     string
+		albumPerformer string
+		file           string
+		tracks         []cueTrack
+		track          *cueTrack
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := cueFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("cue sheet: line %d: missing file name", lineNumber)
+			}
+			file = locateCueFile(resolvePlaylistPath(base, fields[1]))
+			track = nil
+		case "TRACK":
+			if file == "" {
+				return nil, fmt.Errorf("cue sheet: line %d: track before file", lineNumber)
+			}
+			track = nil
+			if len(fields) > 2 && strings.ToUpper(fields[2]) == "AUDIO" {
+				number, _ := strconv.Atoi(fields[1])
+				tracks = append(tracks, cueTrack{file: file, number: number})
+				track = &tracks[len(tracks)-1]
+			}
+		case "TITLE":
+			if len(fields) < 2 {
+				continue
+			}
+			if track != nil {
+				track.title = fields[1]
+			} else if file == "" {
+				albumTitle = fields[1]
+			}
+		case "PERFORMER":
+			if len(fields) < 2 {
+				continue
+			}
+			if track != nil {
+				track.performer = fields[1]
+			} else if file == "" {
+				albumPerformer = fields[1]
+			}
+		case "INDEX":
+			if track == nil || len(fields) < 3 || fields[1] != "01" {
+				continue
+			}
+			start, err := parseCueTime(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("cue sheet: line %d: %w", lineNumber, err)
+			}
+			track.start = start
+			track.indexed = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var items []playlistItem
+	for i, t := range tracks {
+		if !t.indexed {
+			continue
+		}
+		item := playlistItem{
+			path:     t.file,
+			title:    t.title,
+			artist:   t.performer,
+			album:    albumTitle,
+			track:    t.number,
+			duration: -1,
+			start:    t.start,
+		}
+		if item.artist == "" {
+			item.artist = albumPerformer
+		}
+		if item.title == "" {
+			item.title = fmt.Sprintf("Track %02d", len(items)+1)
+		}
+		if i+1 < len(tracks) && tracks[i+1].file == t.file && tracks[i+1].indexed {
+			item.end = tracks[i+1].start
+			item.duration = item.end - item.start
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// cueFields split the line by space, quoted field keep its spaces
+func cueFields(line string) []string {
+	var (
+		fields []string
+		field  strings.Builder
+		quoted bool
+		inside bool
+	)
+	for _, r := range strings.TrimSpace(line) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inside = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inside {
+				fields = append(fields, field.String())
+				field.Reset()
+				inside = false
+			}
+		default:
+			field.WriteRune(r)
+			inside = true
+		}
+	}
+	if inside {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// parseCueTime parse mm:ss:ff, there are 75 frames per second
+func parseCueTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid index time %q", s)
+	}
+	var values [3]int
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid index time %q", s)
+		}
+		values[i] = value
+	}
+	if values[1] >= 60 || values[2] >= 75 {
+		return 0, fmt.Errorf("invalid index time %q", s)
+	}
+	return time.Duration(values[0])*time.Minute + time.Duration(values[1])*time.Second + time.Duration(values[2])*time.Second/75, nil
+}
+
+// locateCueFile fallback to the file with same name but other supported extension, since sheet often still refer to the wav before compressed
+func locateCueFile(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".flac", ".wav", ".mp3"} {
+		if _, err := os.Stat(stem + ext); err == nil {
+			return stem + ext
+		}
+	}
+	return path
+}
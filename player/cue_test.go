@@ -0,0 +1,94 @@
+package player
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCUE(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "album.flac"), nil, 0644))
+	sheet := `REM GENRE Jazz
+PERFORMER "Album Artist"
+TITLE "Live At Somewhere"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Second Song"
+    PERFORMER "Guest"
+    INDEX 00 04:58:00
+    INDEX 01 05:00:37
+  TRACK 03 AUDIO
+    INDEX 01 09:10:74
+FILE "bonus.wav" WAVE
+  TRACK 04 AUDIO
+    TITLE "Bonus"
+    INDEX 01 00:00:00
+`
+
+	t.Run("expand virtual tracks", func(t *testing.T) {
+		items, err := readPlaylist(strings.NewReader(sheet), filepath.Join(dir, "album.cue"))
+		assert.NoError(t, err)
+		second := 5*time.Minute + 37*time.Second/75
+		third := 9*time.Minute + 10*time.Second + 74*time.Second/75
+		assert.Equal(t, []playlistItem{
+			{path: filepath.Join(dir, "album.flac"), title: "Opening", artist: "Album Artist", album: "Live At Somewhere", track: 1, duration: second, end: second},
+			{path: filepath.Join(dir, "album.flac"), title: "Second Song", artist: "Guest", album: "Live At Somewhere", track: 2, duration: third - second, start: second, end: third},
+			{path: filepath.Join(dir, "album.flac"), title: "Track 03", artist: "Album Artist", album: "Live At Somewhere", track: 3, duration: -1, start: third},
+			{path: filepath.Join(dir, "bonus.wav"), title: "Bonus", artist: "Album Artist", album: "Live At Somewhere", track: 4, duration: -1},
+		}, items)
+	})
+	t.Run("latin-1 sheet", func(t *testing.T) {
+		items, err := readCUE(strings.NewReader("FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nTITLE \"Caf\xe9\"\nINDEX 01 00:00:00\n"), dir)
+		assert.NoError(t, err)
+		assert.Equal(t, "Café", items[0].title)
+	})
+	t.Run("invalid index", func(t *testing.T) {
+		for _, index := range []string{"1:00", "00:60:00", "00:00:75", "aa:00:00"} {
+			_, err := readCUE(strings.NewReader("FILE a.wav WAVE\nTRACK 01 AUDIO\nINDEX 01 "+index+"\n"), dir)
+			assert.Error(t, err, index)
+		}
+	})
+	t.Run("track before file", func(t *testing.T) {
+		_, err := readCUE(strings.NewReader("TRACK 01 AUDIO\n"), dir)
+		assert.Error(t, err)
+	})
+	t.Run("export round trip", func(t *testing.T) {
+		sheetPath := filepath.Join(dir, "album.cue")
+		assert.NoError(t, os.WriteFile(sheetPath, []byte(sheet), 0644))
+		items, err := readCUE(strings.NewReader(sheet), dir)
+		assert.NoError(t, err)
+		var songs []song
+		for _, item := range items[:3] {
+			songs = append(songs, song{path: item.path, name: filepath.Base(item.path), title: item.title, duration: item.duration, start: item.start, end: item.end})
+		}
+		songs = append(songs, song{path: filepath.Join(dir, "other.wav"), name: "other.wav", title: "Other", duration: time.Second})
+		exported, err := playlistItems(songs)
+		assert.NoError(t, err)
+		assert.Equal(t, []playlistItem{
+			{path: sheetPath, duration: -1},
+			{path: filepath.Join(dir, "other.wav"), title: "Other", duration: time.Second},
+		}, exported, "the sheet once")
+		for _, name := range []string{"a.m3u8", "a.pls", "a.xspf"} {
+			written := &bytes.Buffer{}
+			assert.NoError(t, writePlaylist(written, filepath.Join(dir, name), exported))
+			read, err := readPlaylist(written, filepath.Join(dir, name))
+			assert.NoError(t, err)
+			assert.Len(t, read, 5, name)
+			assert.Equal(t, items[1].start, read[1].start, name)
+			assert.Equal(t, items[1].end, read[1].end, name)
+			assert.Equal(t, filepath.Join(dir, "other.wav"), read[4].path, name)
+		}
+
+		assert.ErrorIs(t, writePlaylist(&bytes.Buffer{}, filepath.Join(dir, "a.m3u"), items), ErrVirtualTrack)
+		_, err = playlistItems([]song{{path: filepath.Join(dir, "nosheet.flac"), start: time.Second}})
+		assert.ErrorIs(t, err, ErrNoCueSheet)
+	})
+}
@@ -0,0 +1,56 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"path/filepath"
+	"slices"
+)
+
+// drop import the dropped files and directories into the selected playlist, at the drop position when dropped onto its list
+func (p *Player) drop(position fyne.Position, uris []fyne.URI) {
+	index := slices.IndexFunc(p.playlists, func(pl *Playlist) bool {
+		return pl.tab == p.UI.Playlists.Selected()
+	})
+	if index == -1 || len(uris) == 0 {
+		return
+	}
+	pl := p.playlists[index]
+	var paths []string
+	for _, uri := range uris {
+		if uri.Scheme() == "file" {
+			paths = append(paths, uri.Path())
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	at := len(pl.songs)
+	list := pl.UI.List
+	listPosition := fyne.CurrentApp().Driver().AbsolutePositionForObject(list)
+	if list.Visible() && position.X >= listPosition.X && position.X <= listPosition.X+list.Size().Width &&
+		position.Y >= listPosition.Y && position.Y <= listPosition.Y+list.Size().Height {
+		if pl.rowHeight == 0 {
+			pl.rowHeight = list.CreateItem().MinSize().Height + list.Theme().Size(theme.SizeNamePadding)
+		}
+		at = pl.songIndex(dropIndex(position.Y-listPosition.Y+list.GetScrollOffset(), pl.rowHeight, pl.rowCount()))
+	}
+	title := "Import " + filepath.Base(paths[0])
+	if len(paths) > 1 {
+		title = fmt.Sprintf("Import %d items", len(paths))
+	}
+	options := p.scan
+	p.startImport(pl, title, at, false, false, func(ctx context.Context) ([]playlistItem, []string, error) {
+		return collectDropped(ctx, paths, options)
+	})
+}
+
+// dropIndex find the insert index for y in the list content, dropping onto the lower half of a row insert after it
+func dropIndex(y, rowHeight float32, count int) int {
+	if rowHeight <= 0 {
+		return count
+	}
+	return min(max(int((y+rowHeight/2)/rowHeight), 0), count)
+}
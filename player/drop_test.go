@@ -0,0 +1,23 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDropIndex(t *testing.T) {
+	for _, tc := range []struct {
+		y    float32
+		want int
+	}{
+		{-5, 0},
+		{4, 0},
+		{6, 1},
+		{24, 2},
+		{36, 3},
+		{1000, 3},
+	} {
+		assert.Equal(t, tc.want, dropIndex(tc.y, 10, 3), tc.y)
+	}
+	assert.Equal(t, 3, dropIndex(5, 0, 3))
+}
@@ -0,0 +1,53 @@
+package player
+
+import (
+	"errors"
+	"github.com/gorgemul/musicplayer/engine"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidPresetName = errors.New("equalizer preset: name should not be empty nor the name of a built-in preset")
+
+// equalizerPresets are built in, they can not be overwritten nor deleted
+var equalizerPresets = []struct {
+	name      string
+	equalizer engine.Equalizer
+}{
+	{"Flat", engine.Equalizer{}},
+	{"Rock", engine.Equalizer{Preamp: -5, Gains: [engine.EqualizerBands]float64{5, 4, 3, 1, -1, -1, 1, 3, 4, 5}}},
+	{"Classical", engine.Equalizer{Gains: [engine.EqualizerBands]float64{0, 0, 0, 0, 0, 0, -4, -4, -4, -6}}},
+	{"Voice", engine.Equalizer{Preamp: -4, Gains: [engine.EqualizerBands]float64{-4, -3, -2, 1, 3, 4, 4, 3, 1, -2}}},
+	{"Bass boost", engine.Equalizer{Preamp: -6, Gains: [engine.EqualizerBands]float64{6, 6, 5, 3, 1, 0, 0, 0, 0, 0}}},
+}
+
+// presetNames list the built-in presets then the user presets by name
+func presetNames(user map[string]engine.Equalizer) []string {
+	var names []string
+	for _, preset := range equalizerPresets {
+		names = append(names, preset.name)
+	}
+	return append(names, slices.SortedFunc(maps.Keys(user), naturalCompare)...)
+}
+
+// findPreset look the name up in the built-in presets then in the user presets
+func findPreset(user map[string]engine.Equalizer, name string) (eq engine.Equalizer, builtIn bool, ok bool) {
+	for _, preset := range equalizerPresets {
+		if preset.name == name {
+			return preset.equalizer, true, true
+		}
+	}
+	eq, ok = user[name]
+	return eq, false, ok
+}
+
+// savePreset add or replace the user preset, returning the trimmed name it is saved as
+func savePreset(user map[string]engine.Equalizer, name string, eq engine.Equalizer) (string, error) {
+	name = strings.TrimSpace(name)
+	if _, builtIn, _ := findPreset(nil, name); name == "" || builtIn {
+		return "", ErrInvalidPresetName
+	}
+	user[name] = eq
+	return name, nil
+}
@@ -0,0 +1,43 @@
+package player
+
+import (
+	"github.com/gorgemul/musicplayer/engine"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEqualizerPresets(t *testing.T) {
+	user := map[string]engine.Equalizer{}
+	loud := engine.Equalizer{Preamp: 3}
+
+	t.Run("save", func(t *testing.T) {
+		name, err := savePreset(user, "  Night 10 ", loud)
+		assert.NoError(t, err)
+		assert.Equal(t, "Night 10", name)
+		_, err = savePreset(user, "Night 9", engine.Equalizer{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Flat", "Rock", "Classical", "Voice", "Bass boost", "Night 9", "Night 10"}, presetNames(user))
+	})
+	t.Run("built-in names are taken", func(t *testing.T) {
+		_, err := savePreset(user, "Rock", loud)
+		assert.ErrorIs(t, err, ErrInvalidPresetName)
+		_, err = savePreset(user, " ", loud)
+		assert.ErrorIs(t, err, ErrInvalidPresetName)
+	})
+	t.Run("find", func(t *testing.T) {
+		eq, builtIn, ok := findPreset(user, "Night 10")
+		assert.True(t, ok)
+		assert.False(t, builtIn)
+		assert.Equal(t, loud, eq)
+		eq, builtIn, ok = findPreset(user, "Bass boost")
+		assert.True(t, ok && builtIn)
+		assert.Greater(t, eq.Gains[0], 0.0)
+		_, _, ok = findPreset(user, "missing")
+		assert.False(t, ok)
+	})
+	t.Run("band label", func(t *testing.T) {
+		assert.Equal(t, "31", bandLabel(31))
+		assert.Equal(t, "1k", bandLabel(1000))
+		assert.Equal(t, "16k", bandLabel(16000))
+	})
+}
@@ -0,0 +1,135 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/gorgemul/musicplayer/engine"
+	"log"
+	"strconv"
+)
+
+// equalizerRange is the gain in dB a slider go down and up to
+const equalizerRange = 12
+
+// showEqualizer open the equalizer, a change is heard right away and saved once the slider is released
+func (p *Player) showEqualizer() {
+	eq := p.settings.Equalizer
+	applying := false // whether the sliders are moved by a preset
+	apply := func() {
+		p.settings.Equalizer = eq
+		p.engine.SetEqualizer(eq)
+	}
+	save := func() {
+		if err := saveSettings(p.settings); err != nil {
+			log.Println("saveSettings", err)
+		}
+	}
+	presets := widget.NewSelect(presetNames(p.settings.EqualizerPresets), nil)
+	presets.PlaceHolder = "Custom"
+	for _, name := range presets.Options {
+		if preset, _, _ := findPreset(p.settings.EqualizerPresets, name); preset == eq {
+			presets.Selected = name
+			break
+		}
+	}
+	newSlider := func(value *float64) *widget.Slider {
+		slider := widget.NewSlider(-equalizerRange, equalizerRange)
+		slider.Orientation = widget.Vertical
+		slider.Step = 0.5
+		slider.Value = *value
+		slider.OnChanged = func(v float64) {
+			*value = v
+			if applying {
+				return
+			}
+			presets.ClearSelected() // no longer the preset
+			apply()
+		}
+		slider.OnChangeEnded = func(float64) {
+			if !applying {
+				save()
+			}
+		}
+		return slider
+	}
+	sliders := []*widget.Slider{newSlider(&eq.Preamp)}
+	for i := range eq.Gains {
+		sliders = append(sliders, newSlider(&eq.Gains[i]))
+	}
+	var deleteBtn *widget.Button
+	presets.OnChanged = func(name string) {
+		preset, builtIn, ok := findPreset(p.settings.EqualizerPresets, name)
+		if builtIn || !ok {
+			deleteBtn.Disable()
+		} else {
+			deleteBtn.Enable()
+		}
+		if !ok {
+			return
+		}
+		applying = true
+		sliders[0].SetValue(preset.Preamp)
+		for i, gain := range preset.Gains {
+			sliders[i+1].SetValue(gain)
+		}
+		applying = false
+		eq = preset
+		apply()
+		save()
+	}
+	saveBtn := widget.NewButtonWithIcon("save preset", theme.DocumentSaveIcon(), func() {
+		name := widget.NewEntry()
+		if _, builtIn, ok := findPreset(p.settings.EqualizerPresets, presets.Selected); ok && !builtIn {
+			name.SetText(presets.Selected)
+		}
+		dialog.ShowForm("Save equalizer preset", "Save", "Cancel", []*widget.FormItem{widget.NewFormItem("Name", name)}, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if p.settings.EqualizerPresets == nil {
+				p.settings.EqualizerPresets = map[string]engine.Equalizer{}
+			}
+			saved, err := savePreset(p.settings.EqualizerPresets, name.Text, eq)
+			if err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			save()
+			presets.SetOptions(presetNames(p.settings.EqualizerPresets))
+			presets.SetSelected(saved)
+		}, p.window)
+	})
+	deleteBtn = widget.NewButtonWithIcon("delete preset", theme.DeleteIcon(), func() {
+		delete(p.settings.EqualizerPresets, presets.Selected)
+		save()
+		presets.SetOptions(presetNames(p.settings.EqualizerPresets))
+		presets.ClearSelected()
+	})
+	if _, builtIn, ok := findPreset(p.settings.EqualizerPresets, presets.Selected); builtIn || !ok {
+		deleteBtn.Disable()
+	}
+	bands := container.NewGridWithColumns(len(sliders))
+	for i, slider := range sliders {
+		label := "Preamp"
+		if i > 0 {
+			label = bandLabel(engine.EqualizerFrequencies[i-1])
+		}
+		bands.Add(container.NewBorder(nil, widget.NewLabelWithStyle(label, fyne.TextAlignCenter, fyne.TextStyle{}), nil, nil, slider))
+	}
+	top := container.NewBorder(nil, nil, widget.NewLabel("Preset"), container.NewHBox(saveBtn, deleteBtn), presets)
+	equalizerDialog := dialog.NewCustom("Equalizer", "Close", container.NewBorder(top, nil, nil, nil, bands), p.window)
+	windowSize := p.window.Canvas().Size()
+	equalizerDialog.Resize(fyne.NewSize(windowSize.Width*0.6, windowSize.Height*0.6))
+	equalizerDialog.Show()
+}
+
+// bandLabel write the frequency as 125 or 2k
+func bandLabel(frequency float64) string {
+	if frequency >= 1000 {
+		return strconv.FormatFloat(frequency/1000, 'f', -1, 64) + "k"
+	}
+	return strconv.FormatFloat(frequency, 'f', -1, 64)
+}
@@ -0,0 +1,65 @@
+package player
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// foldText lower the text and strip its accents, so "Café" match "cafe"
+func foldText(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// matchSong report whether every word of the folded query is in the title, artist, album or filename
+func matchSong(words []string, info metadata, path string) bool {
+	text := foldText(strings.Join([]string{info.title, info.artist, info.album, filepath.Base(path)}, "\n"))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Player) applyFilter(pl *Playlist) {
+	words := strings.Fields(foldText(pl.filter))
+	if len(words) == 0 {
+		pl.visible = nil
+		return
+	}
+	pl.visible = []int{}
+	for i, s := range pl.songs {
+		if matchSong(words, p.songInfo(s), s.path) {
+			pl.visible = append(pl.visible, i)
+		}
+	}
+}
+
+func (pl *Playlist) rowCount() int {
+	if pl.visible == nil {
+		return len(pl.songs)
+	}
+	return len(pl.visible)
+}
+
+// songIndex map the list row to the index in songs, the row after the last one map to after the last visible song
+func (pl *Playlist) songIndex(row int) int {
+	switch {
+	case pl.visible == nil:
+		return row
+	case row < len(pl.visible):
+		return pl.visible[row]
+	case len(pl.visible) == 0:
+		return len(pl.songs)
+	}
+	return pl.visible[len(pl.visible)-1] + 1
+}
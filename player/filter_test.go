@@ -0,0 +1,37 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	t.Run("fold", func(t *testing.T) {
+		assert.Equal(t, "cafe creme", foldText("Café CRÈME"))
+	})
+	t.Run("match", func(t *testing.T) {
+		info := metadata{title: "Café del Mar", artist: "Björk", album: "Homogenic"}
+		for query, want := range map[string]bool{
+			"":              true,
+			"cafe":          true,
+			"BJORK homo":    true,
+			"01 intro":      true,
+			"bjork missing": false,
+		} {
+			assert.Equal(t, want, matchSong(strings.Fields(foldText(query)), info, "/music/01 Intro.mp3"), query)
+		}
+	})
+	t.Run("rows", func(t *testing.T) {
+		pl := Playlist{songs: make([]song, 5)}
+		assert.Equal(t, 5, pl.rowCount())
+		assert.Equal(t, 3, pl.songIndex(3))
+		pl.visible = []int{1, 3}
+		assert.Equal(t, 2, pl.rowCount())
+		assert.Equal(t, 3, pl.songIndex(1))
+		assert.Equal(t, 4, pl.songIndex(2))
+		pl.visible = []int{}
+		assert.Equal(t, 0, pl.rowCount())
+		assert.Equal(t, 5, pl.songIndex(0))
+	})
+}
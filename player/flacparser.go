@@ -0,0 +1,167 @@
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/gorgemul/musicplayer/static"
+	"image"
+	_ "image/jpeg"
+	"io"
+	"log"
+	"strings"
+)
+
+var (
+	ErrInvalidFLACIdentifier = errors.New("flac: invalid identifier")
+	ErrInvalidFLACBlock      = errors.New("flac: invalid metadata block")
+)
+
+const (
+	flacVorbisComment = 4
+	flacPicture       = 6
+)
+
+/*
+"fLaC"
+Metadata block header   last-metadata-block flag(1 bit) block type(7 bits) length(24 bits)
+Metadata block data     <length bytes>
+...
+*/
+func parseFLAC(r io.Reader, withCover bool) (Album, error) {
+	var album Album
+	identifier := make([]byte, 4)
+	if _, err := io.ReadFull(r, identifier); err != nil || string(identifier) != "fLaC" {
+		return Album{}, ErrInvalidFLACIdentifier
+	}
+	for last := false; !last; {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return Album{}, ErrInvalidFLACBlock
+		}
+		last = header[0]&0x80 > 0
+		blockType := header[0] & 0x7f
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if blockType != flacVorbisComment && (blockType != flacPicture || !withCover) {
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return Album{}, ErrInvalidFLACBlock
+			}
+			continue
+		}
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return Album{}, ErrInvalidFLACBlock
+		}
+		if blockType == flacVorbisComment {
+			comments, err := parseVorbisComment(block)
+			if err != nil {
+				return Album{}, err
+			}
+			album.Title = comments["TITLE"]
+			album.Artist = comments["ARTIST"]
+			album.AlbumTitle = comments["ALBUM"]
+			album.Genre = comments["GENRE"]
+			album.Year = parseNumber(comments["DATE"])
+			album.Track = parseNumber(comments["TRACKNUMBER"])
+			album.Disc = parseNumber(comments["DISCNUMBER"])
+			for key, value := range comments {
+				album.ReplayGain.set(key, value)
+			}
+		} else if cover := extractFLACPicture(block); cover != nil && album.Cover == nil {
+			album.Cover = cover
+		}
+	}
+	if album.Cover == nil && withCover {
+		var err error
+		album.Cover, _, err = image.Decode(bytes.NewReader(static.DefaultCoverBytes))
+		if err != nil {
+			log.Fatal("default image should be correctly open:", err)
+		}
+	}
+	if album.Artist == "" {
+		album.Artist = "Unknown Artist"
+	}
+	if album.Title == "" {
+		album.Title = "Unknown Title"
+	}
+	return album, nil
+}
+
+/*
+Vendor length       <32 bits little endian>
+Vendor string       <vendor length bytes>
+Comment count       <32 bits little endian>
+Comment length      <32 bits little endian>
+Comment             <comment length bytes> KEY=value
+...
+*/
+// parseVorbisComment return comments keyed by upper case field name, first value win when field repeat
+func parseVorbisComment(block []byte) (map[string]string, error) {
+	comments := map[string]string{}
+	next := func() ([]byte, error) {
+		if len(block) < 4 {
+			return nil, ErrInvalidFLACBlock
+		}
+		size := binary.LittleEndian.Uint32(block)
+		if uint64(len(block)-4) < uint64(size) {
+			return nil, ErrInvalidFLACBlock
+		}
+		field := block[4 : 4+size]
+		block = block[4+size:]
+		return field, nil
+	}
+	if _, err := next(); err != nil { // vendor
+		return nil, err
+	}
+	if len(block) < 4 {
+		return nil, ErrInvalidFLACBlock
+	}
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+	for range count {
+		comment, err := next()
+		if err != nil {
+			return nil, err
+		}
+		key, value, found := strings.Cut(string(comment), "=")
+		key = strings.ToUpper(key)
+		if _, exist := comments[key]; found && !exist {
+			comments[key] = value
+		}
+	}
+	return comments, nil
+}
+
+/*
+Picture type        <32 bits>
+MIME type length    <32 bits>
+MIME type           <mime type length bytes>
+Description length  <32 bits>
+Description         <description length bytes>
+Width, height, color depth, colors used <4 * 32 bits>
+Picture data length <32 bits>
+Picture data        <binary data>
+*/
+func extractFLACPicture(block []byte) image.Image {
+	offset := uint64(4)
+	for range 2 { // mime type and description
+		if uint64(len(block)) < offset+4 {
+			return nil
+		}
+		offset += 4 + uint64(binary.BigEndian.Uint32(block[offset:]))
+	}
+	offset += 16
+	if uint64(len(block)) < offset+4 {
+		return nil
+	}
+	size := uint64(binary.BigEndian.Uint32(block[offset:]))
+	offset += 4
+	if uint64(len(block)) < offset+size {
+		return nil
+	}
+	cover, _, err := image.Decode(bytes.NewReader(block[offset : offset+size]))
+	if err != nil {
+		return nil
+	}
+	return cover
+}
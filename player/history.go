@@ -0,0 +1,169 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// historyEntry is a song heard, from the time it started to how far it got before the player moved on
+type historyEntry struct {
+	Time     time.Time     `json:"time"`
+	Path     string        `json:"path"`
+	Start    time.Duration `json:"start,omitempty"` // start of the virtual track in the file
+	Title    string        `json:"title,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	Album    string        `json:"album,omitempty"`
+	Position time.Duration `json:"position"`
+	Duration time.Duration `json:"duration"`
+	Skipped  bool          `json:"skipped,omitempty"` // left for another song before the end
+}
+
+// historyKey identify the song of an entry, virtual tracks of a file are told apart by their start
+type historyKey struct {
+	path  string
+	start time.Duration
+}
+
+func (e historyEntry) key() historyKey {
+	return historyKey{e.Path, e.Start}
+}
+
+func (s song) historyKey() historyKey {
+	return historyKey{s.path, s.start}
+}
+
+// played report whether enough of the entry is heard to count as a play
+func (e historyEntry) played(s settings) bool {
+	if e.Duration <= 0 {
+		return false
+	}
+	if s.PlaySeconds > 0 && e.Position >= time.Duration(s.PlaySeconds)*time.Second {
+		return true
+	}
+	return e.Position >= e.Duration*time.Duration(s.PlayPercent)/100
+}
+
+type trackStats struct {
+	plays      int
+	skips      int
+	lastPlayed time.Time
+}
+
+// add count the entry as a play or a skip, skipping after the play threshold still count as a play
+func (t trackStats) add(e historyEntry, s settings) trackStats {
+	switch {
+	case e.played(s):
+		t.plays++
+		if e.Time.After(t.lastPlayed) {
+			t.lastPlayed = e.Time
+		}
+	case e.Skipped:
+		t.skips++
+	}
+	return t
+}
+
+// historyStats count the plays and skips of every song in the history, they depend on the play threshold of the settings
+func historyStats(history []historyEntry, s settings) map[historyKey]trackStats {
+	stats := map[historyKey]trackStats{}
+	for _, e := range history {
+		stats[e.key()] = stats[e.key()].add(e, s)
+	}
+	return stats
+}
+
+// relocateHistory point the entries at the moved files, report whether any entry is changed
+func relocateHistory(history []historyEntry, moves map[string]string) bool {
+	changed := false
+	for i, e := range history {
+		if newPath, moved := moves[e.Path]; moved {
+			history[i].Path = newPath
+			changed = true
+		}
+	}
+	return changed
+}
+
+func historyFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// loadHistory read the entries oldest first, a broken line such as the one cut by a crash is skipped
+func loadHistory() ([]historyEntry, error) {
+	file, err := historyFile()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var history []historyEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Println("loadHistory", err)
+			continue
+		}
+		history = append(history, e)
+	}
+	return history, scanner.Err()
+}
+
+// appendHistory add a line to the history file, the history is never rewritten for a play
+func appendHistory(e historyEntry) error {
+	file, err := historyFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// saveHistory replace the whole history, write to a temporary file first like saveLibrary
+func saveHistory(history []historyEntry) error {
+	file, err := historyFile()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	for _, e := range history {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
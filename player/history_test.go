@@ -0,0 +1,85 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	start := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+	s := settings{PlayPercent: 50, PlaySeconds: 240}
+
+	t.Run("play threshold", func(t *testing.T) {
+		assert.True(t, historyEntry{Position: 90 * time.Second, Duration: 3 * time.Minute}.played(s))
+		assert.False(t, historyEntry{Position: 89 * time.Second, Duration: 3 * time.Minute}.played(s))
+		assert.True(t, historyEntry{Position: 4 * time.Minute, Duration: 20 * time.Minute}.played(s))
+		assert.False(t, historyEntry{Position: 4 * time.Minute, Duration: 20 * time.Minute}.played(settings{PlayPercent: 50}))
+		assert.False(t, historyEntry{}.played(s))
+	})
+	t.Run("statistics", func(t *testing.T) {
+		history := []historyEntry{
+			{Time: start, Path: "/a.mp3", Position: 3 * time.Minute, Duration: 3 * time.Minute},
+			{Time: start.Add(time.Hour), Path: "/a.mp3", Position: 10 * time.Second, Duration: 3 * time.Minute, Skipped: true},
+			{Time: start.Add(2 * time.Hour), Path: "/a.mp3", Position: 2 * time.Minute, Duration: 3 * time.Minute, Skipped: true},
+			{Time: start, Path: "/album.flac", Start: 5 * time.Minute, Position: time.Minute, Duration: 4 * time.Minute},
+		}
+		assert.Equal(t, map[historyKey]trackStats{
+			{path: "/a.mp3"}: {plays: 2, skips: 1, lastPlayed: start.Add(2 * time.Hour)},
+			{path: "/album.flac", start: 5 * time.Minute}: {},
+		}, historyStats(history, s))
+		assert.Equal(t, map[historyKey]trackStats{
+			{path: "/a.mp3"}: {plays: 1, skips: 2, lastPlayed: start},
+			{path: "/album.flac", start: 5 * time.Minute}: {},
+		}, historyStats(history, settings{PlayPercent: 100}))
+	})
+	t.Run("append, load and save", func(t *testing.T) {
+		history, err := loadHistory()
+		assert.NoError(t, err)
+		assert.Empty(t, history)
+		entries := []historyEntry{
+			{Time: start, Path: "/a.mp3", Title: "A", Position: time.Minute, Duration: 3 * time.Minute, Skipped: true},
+			{Time: start.Add(time.Hour), Path: "/b.mp3", Position: 2 * time.Minute, Duration: 2 * time.Minute},
+		}
+		for _, e := range entries {
+			assert.NoError(t, appendHistory(e))
+		}
+		file, err := historyFile()
+		assert.NoError(t, err)
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0644)
+		assert.NoError(t, err)
+		f.WriteString(`{"time":"2024-06-01T2`) // cut while writing
+		f.Close()
+		history, err = loadHistory()
+		assert.NoError(t, err)
+		assert.Len(t, history, 2)
+		for i := range entries {
+			assert.True(t, entries[i].Time.Equal(history[i].Time))
+			history[i].Time = entries[i].Time
+		}
+		assert.Equal(t, entries, history)
+
+		assert.True(t, relocateHistory(history, map[string]string{"/b.mp3": "/c.mp3"}))
+		assert.False(t, relocateHistory(history, map[string]string{"/b.mp3": "/c.mp3"}))
+		assert.NoError(t, saveHistory(history))
+		loaded, err := loadHistory()
+		assert.NoError(t, err)
+		assert.Equal(t, "/c.mp3", loaded[1].Path)
+	})
+	t.Run("settings", func(t *testing.T) {
+		loaded, err := loadSettings()
+		assert.NoError(t, err)
+		assert.Equal(t, defaultSettings(), loaded)
+		assert.NoError(t, saveSettings(settings{PlayPercent: 80}))
+		loaded, err = loadSettings()
+		assert.NoError(t, err)
+		assert.Equal(t, settings{PlayPercent: 80}, loaded)
+	})
+	t.Run("last played", func(t *testing.T) {
+		assert.Equal(t, "", formatLastPlayed(time.Time{}, start))
+		assert.Equal(t, "18:30", formatLastPlayed(start.Add(-90*time.Minute), start))
+		assert.Equal(t, "2024-05-31", formatLastPlayed(start.Add(-21*time.Hour), start))
+	})
+}
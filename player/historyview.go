@@ -0,0 +1,231 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPlayThreshold = errors.New("play threshold: percent should be 1 to 100 and seconds not negative")
+
+func (p *Player) newHistoryView() {
+	var err error
+	if p.settings, err = loadSettings(); err != nil {
+		log.Println("loadSettings", err)
+	}
+	if p.history, err = loadHistory(); err != nil {
+		log.Println("loadHistory", err)
+	}
+	p.stats = historyStats(p.history, p.settings)
+	p.UI.History = widget.NewList(
+		func() int {
+			return len(p.history)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("historyEntry")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			e := p.history[len(p.history)-1-i] // newest first
+			o.(*widget.Label).SetText(historyText(e, p.settings))
+		})
+	p.UI.HistoryThresholdBtn = widget.NewButtonWithIcon("play threshold", theme.SettingsIcon(), p.showPlayThreshold)
+	p.UI.HistoryExportBtn = widget.NewButtonWithIcon("export", theme.UploadIcon(), p.exportHistory)
+	p.UI.HistoryReportBtn = widget.NewButtonWithIcon("report", theme.InfoIcon(), p.showReport)
+}
+
+// historyText describe the entry as a line of the history view
+func historyText(e historyEntry, s settings) string {
+	title := e.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(e.Path), filepath.Ext(e.Path))
+	}
+	if e.Artist != "" {
+		title += " - " + e.Artist
+	}
+	text := fmt.Sprintf("%s  %s  %s / %s", e.Time.Format("2006-01-02 15:04"), title, formatTime(e.Position.Seconds()), formatTime(e.Duration.Seconds()))
+	if !e.played(s) && e.Skipped {
+		text += "  (skipped)"
+	}
+	return text
+}
+
+// historyEntry describe how far the current song got
+func (p *Player) historyEntry(position, duration time.Duration, skipped bool) historyEntry {
+	info := p.songInfo(p.current.song)
+	return historyEntry{
+		Time:     p.current.started,
+		Path:     p.current.song.path,
+		Start:    p.current.song.start,
+		Title:    info.title,
+		Artist:   info.artist,
+		Album:    info.album,
+		Position: position,
+		Duration: duration,
+		Skipped:  skipped,
+	}
+}
+
+// addHistory log the entry and update the statistics shown by the playlists
+func (p *Player) addHistory(e historyEntry) {
+	if err := appendHistory(e); err != nil {
+		log.Println("appendHistory", err)
+	}
+	p.history = append(p.history, e)
+	p.stats[e.key()] = p.stats[e.key()].add(e, p.settings)
+	p.UI.History.Refresh()
+	p.refreshStats()
+}
+
+// refreshStats show the changed statistics, smart playlists may match other songs now
+func (p *Player) refreshStats() {
+	for _, pl := range p.playlists {
+		if pl.smart != nil {
+			p.setSmart(pl, pl.smart)
+		} else {
+			p.refreshPlaylist(pl)
+		}
+	}
+}
+
+// relocateHistory keep the history of the moved files
+func (p *Player) relocateHistory(moves map[string]string) {
+	if !relocateHistory(p.history, moves) {
+		return
+	}
+	if err := saveHistory(p.history); err != nil {
+		log.Println("saveHistory", err)
+	}
+	p.stats = historyStats(p.history, p.settings)
+	p.refreshStats()
+}
+
+func (p *Player) showPlayThreshold() {
+	percent := widget.NewEntry()
+	percent.SetText(strconv.Itoa(p.settings.PlayPercent))
+	seconds := widget.NewEntry()
+	seconds.SetText(strconv.Itoa(p.settings.PlaySeconds))
+	items := []*widget.FormItem{
+		widget.NewFormItem("Percent heard", percent),
+		widget.NewFormItem("Or seconds heard", seconds),
+	}
+	items[1].HintText = "0 to only count the percent"
+	dialog.ShowForm("Count a play after", "Save", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		s := p.settings
+		var percentErr, secondsErr error
+		s.PlayPercent, percentErr = strconv.Atoi(strings.TrimSpace(percent.Text))
+		s.PlaySeconds, secondsErr = strconv.Atoi(strings.TrimSpace(seconds.Text))
+		if percentErr != nil || secondsErr != nil || s.PlayPercent < 1 || s.PlayPercent > 100 || s.PlaySeconds < 0 {
+			dialog.ShowError(ErrInvalidPlayThreshold, p.window)
+			return
+		}
+		if err := saveSettings(s); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		p.settings = s
+		p.stats = historyStats(p.history, p.settings)
+		p.UI.History.Refresh()
+		p.refreshStats()
+	}, p.window)
+}
+
+func (p *Player) exportHistory() {
+	saveDialog := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+		if err != nil || file == nil {
+			log.Println("dialog.NewFileSave", err)
+			return
+		}
+		defer file.Close()
+		if err := writeHistory(file, file.URI().Path(), p.history, p.settings); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	}, p.window)
+	saveDialog.SetFilter(storage.NewExtensionFileFilter(exportHistoryExtensions))
+	saveDialog.SetFileName("history.csv")
+	windowSize := p.window.Canvas().Size()
+	saveDialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+	saveDialog.Show()
+}
+
+// showReport summarize the history of a date range, this year so far by default
+func (p *Player) showReport() {
+	now := time.Now()
+	from := widget.NewEntry()
+	from.SetText(time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.Local).Format(time.DateOnly))
+	to := widget.NewEntry()
+	to.SetText(now.Format(time.DateOnly))
+	result := container.NewStack()
+	show := func() {
+		start, end, err := parseReportRange(from.Text, to.Text)
+		if err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		result.Objects = []fyne.CanvasObject{reportView(buildReport(p.history, p.settings, start, end, 10))}
+		result.Refresh()
+	}
+	from.OnSubmitted = func(string) { show() }
+	to.OnSubmitted = func(string) { show() }
+	rangeForm := container.NewHBox(
+		widget.NewLabel("From"), from,
+		widget.NewLabel("To"), to,
+		widget.NewButtonWithIcon("show", theme.SearchIcon(), show),
+	)
+	show()
+	reportDialog := dialog.NewCustom("Listening report", "Close", container.NewBorder(rangeForm, nil, nil, nil, result), p.window)
+	windowSize := p.window.Canvas().Size()
+	reportDialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+	reportDialog.Show()
+}
+
+func reportView(report historyReport) fyne.CanvasObject {
+	topList := func(title string, counts []reportCount) fyne.CanvasObject {
+		box := container.NewVBox(widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for i, c := range counts {
+			label := widget.NewLabel(fmt.Sprintf("%d. %s  %d plays, %s", i+1, c.name, c.plays, formatTime(c.listening.Seconds())))
+			label.Truncation = fyne.TextTruncateEllipsis
+			box.Add(label)
+		}
+		if len(counts) == 0 {
+			box.Add(widget.NewLabel("Nothing played"))
+		}
+		return box
+	}
+	hourLabels := make([]string, 24)
+	hours := make([]float64, 24)
+	for hour, listening := range report.hours {
+		hourLabels[hour] = strconv.Itoa(hour)
+		hours[hour] = listening.Minutes()
+	}
+	weekdayLabels := make([]string, 7)
+	weekdays := make([]float64, 7)
+	for day := range 7 {
+		weekday := time.Weekday((day + 1) % 7) // monday first
+		weekdayLabels[day] = weekday.String()[:3]
+		weekdays[day] = report.weekdays[weekday].Minutes()
+	}
+	return container.NewVScroll(container.NewVBox(
+		widget.NewLabelWithStyle(fmt.Sprintf("%d plays, %s listened", report.plays, formatTime(report.listening.Seconds())), fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		container.NewGridWithColumns(3, topList("Top artists", report.artists), topList("Top albums", report.albums), topList("Top tracks", report.tracks)),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Listening by hour of day", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		newBarChart(hourLabels, hours),
+		widget.NewLabelWithStyle("Listening by day of week", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		newBarChart(weekdayLabels, weekdays),
+	))
+}
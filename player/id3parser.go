@@ -11,6 +11,9 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
 )
 
 var (
@@ -18,15 +21,28 @@ var (
 	ErrInvalidTagHeaderVersion    = errors.New("tag header: invalid version")
 	ErrInvalidTagHeaderflags      = errors.New("tag header: invalid flags")
 	ErrInvalidTagHeaderSize       = errors.New("tag header: invalid size")
+	ErrInvalidFrameSize           = errors.New("tag frame: invalid size")
+	ErrInvalidCover               = errors.New("tag frame: invalid apic cover")
 )
 
 type Album struct {
-	Artist string
-	Title  string
-	Cover  image.Image // if no image found in meta data, use defulat image
+	Artist     string
+	Title      string
+	AlbumTitle string      // empty when not tagged
+	Genre      string      // empty when not tagged
+	Year       int         // 0 when not tagged
+	Cover      image.Image // if no image found in meta data, use defulat image
+	Track      int         // 0 when not tagged
+	Disc       int         // 0 when not tagged
+	ReplayGain ReplayGain
 }
 
 func parse(fileStream []byte) (Album, error) {
+	return parseTag(fileStream, true)
+}
+
+// parseTag skip decoding the cover when withCover is false, which is much faster when only text frames are needed
+func parseTag(fileStream []byte, withCover bool) (Album, error) {
 	var (
 		advanceBytes uint32
 		album        Album
@@ -36,14 +52,20 @@ func parse(fileStream []byte) (Album, error) {
 	if err := checkValidTagHeader(fileStream, &advanceBytes); err != nil {
 		return Album{}, err
 	}
+	version := fileStream[3]
+	fileStream = fileStream[:min(10+int(synchsafe(fileStream[6:10])), len(fileStream))] // the audio follow the tag
 	extendedHeaderExist := (uint8(fileStream[5]) & 0b01000000) > 1
 	if extendedHeaderExist {
-		advanceExtendedHeader(fileStream, &advanceBytes)
+		if err := advanceExtendedHeader(fileStream, version, &advanceBytes); err != nil {
+			return Album{}, err
+		}
 	}
 	for !stop {
-		advanceFrame(fileStream, &advanceBytes, &album, &stop)
+		if err := advanceFrame(fileStream, version, &advanceBytes, &album, &stop, withCover); err != nil {
+			return Album{}, err
+		}
 	}
-	if album.Cover == nil {
+	if album.Cover == nil && withCover {
 		album.Cover, _, err = image.Decode(bytes.NewReader(static.DefaultCoverBytes))
 		if err != nil {
 			log.Fatal("default image should be correctly open:", err)
@@ -59,6 +81,9 @@ func parse(fileStream []byte) (Album, error) {
 }
 
 func checkValidTagHeader(fileStream []byte, advanceBytes *uint32) error {
+	if len(fileStream) < 10 {
+		return ErrInvalidTagHeaderIdentifier
+	}
 	if identifier := string(fileStream[:3]); identifier != "ID3" {
 		return ErrInvalidTagHeaderIdentifier
 	}
@@ -81,35 +106,137 @@ func checkValidTagHeader(fileStream []byte, advanceBytes *uint32) error {
 	return nil
 }
 
-func advanceExtendedHeader(fileStream []byte, advanceBytes *uint32) {
+// advanceExtendedHeader skip the extended header, its size is synchsafe and count itself in id3v2.4
+func advanceExtendedHeader(fileStream []byte, version byte, advanceBytes *uint32) error {
+	if len(fileStream) < 14 {
+		return ErrInvalidTagHeaderSize
+	}
 	extendedHeaderSize := binary.BigEndian.Uint32(fileStream[10:14])
-	*advanceBytes += 4
+	if version == 4 {
+		extendedHeaderSize = synchsafe(fileStream[10:14])
+	} else {
+		extendedHeaderSize += 4
+	}
+	if uint64(*advanceBytes)+uint64(extendedHeaderSize) > uint64(len(fileStream)) {
+		return ErrInvalidTagHeaderSize
+	}
 	*advanceBytes += extendedHeaderSize
+	return nil
 }
 
-func advanceFrame(fileStream []byte, advanceBytes *uint32, album *Album, stop *bool) {
-	frameID := string(fileStream[*advanceBytes : *advanceBytes+4])
-	if frameID == "\x00\x00\x00\x00" { // indicating that we have looped over all the frames
+func advanceFrame(fileStream []byte, version byte, advanceBytes *uint32, album *Album, stop *bool, withCover bool) error {
+	frameID, frameSize, err := frameHeader(fileStream[*advanceBytes:], version)
+	if err != nil {
+		return err
+	}
+	if frameID == "" { // indicating that we have looped over all the frames
 		*stop = true
-		return
+		return nil
+	}
+	*advanceBytes += 10
+	content := fileStream[*advanceBytes : *advanceBytes+frameSize]
+	*advanceBytes += frameSize
+	if len(content) < 2 { // not even an encoding byte and a character, nothing to read
+		return nil
 	}
-	*advanceBytes += 4
-	frameSize := binary.BigEndian.Uint32(fileStream[*advanceBytes : *advanceBytes+4])
-	*advanceBytes += 4
-	*advanceBytes += 2 // flags
 	switch frameID {
 	case "TIT2":
-		album.Title = getFrameContent(string(fileStream[*advanceBytes : *advanceBytes+frameSize]))
+		album.Title = getFrameContent(string(content))
 	case "TPE1":
-		album.Artist = getFrameContent(string(fileStream[*advanceBytes : *advanceBytes+frameSize]))
+		album.Artist = getFrameContent(string(content))
+	case "TALB":
+		album.AlbumTitle = getFrameContent(string(content))
+	case "TCON":
+		album.Genre = parseGenre(getFrameContent(string(content)))
+	case "TYER", "TDRC":
+		album.Year = parseNumber(getFrameContent(string(content)))
+	case "TRCK":
+		album.Track = parseNumber(getFrameContent(string(content)))
+	case "TPOS":
+		album.Disc = parseNumber(getFrameContent(string(content)))
+	case "TXXX":
+		album.ReplayGain.set(parseUserText(content))
 	case "APIC":
-		album.Cover = extractCover(fileStream, *advanceBytes, frameSize)
+		if withCover {
+			album.Cover, _ = extractCover(content) // a broken cover is left nil, so the default one is shown
+		}
 	}
-	*advanceBytes += frameSize
+	return nil
+}
+
+// frameHeader read the id and size of the frame at the start of frames, the id is empty at the padding or the end of the tag.
+// The size is synchsafe in id3v2.4, and checked to fit in frames
+func frameHeader(frames []byte, version byte) (id string, size uint32, err error) {
+	if len(frames) < 10 || frames[0] == 0 {
+		return "", 0, nil
+	}
+	size = binary.BigEndian.Uint32(frames[4:8])
+	if version == 4 {
+		size = synchsafe(frames[4:8])
+	}
+	if uint64(size) > uint64(len(frames)-10) {
+		return "", 0, ErrInvalidFrameSize
+	}
+	return string(frames[:4]), size, nil
 }
 
 func getFrameContent(s string) string {
-	return s[1 : len(s)-1] // frame content contain encoding leading byte and null terminator byte
+	return strings.TrimRight(s[1:], "\x00") // frame content contain encoding leading byte and null terminator byte
+}
+
+// parseUserText split the content of a TXXX frame into its description and value
+func parseUserText(content []byte) (description, value string) {
+	if len(content) == 0 {
+		return "", ""
+	}
+	encoding, content := content[0], content[1:]
+	if encoding == 1 || encoding == 2 { // utf-16, terminated by two zero bytes
+		for i := 0; i+1 < len(content); i += 2 {
+			if content[i] == 0 && content[i+1] == 0 {
+				return decodeUTF16(content[:i]), decodeUTF16(content[i+2:])
+			}
+		}
+		return decodeUTF16(content), ""
+	}
+	d, v, _ := bytes.Cut(content, []byte{0})
+	return string(d), strings.TrimRight(string(v), "\x00")
+}
+
+// decodeUTF16 read the text after its byte order mark, big endian without one
+func decodeUTF16(b []byte) string {
+	var order binary.ByteOrder = binary.BigEndian
+	if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+		order, b = binary.LittleEndian, b[2:]
+	} else if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		b = b[2:]
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// parseNumber parse the leading number of a "<position>/<total>" text, 0 if there is none
+func parseNumber(s string) int {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if end == -1 {
+		end = len(s)
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// parseGenre drop the "(number)" id3v1 genre reference when the genre name follow it
+func parseGenre(s string) string {
+	if strings.HasPrefix(s, "(") {
+		if end := strings.Index(s, ")"); end != -1 && end+1 < len(s) {
+			return s[end+1:]
+		}
+	}
+	return s
 }
 
 /*
@@ -119,33 +246,110 @@ Picture type    $xx
 Description     <text string according to encoding> $00 (00)
 Picture data    <binary data>
 */
-func extractCover(fileStream []byte, advanceBytes, frameSize uint32) image.Image {
+func extractCover(content []byte) (image.Image, error) {
 	var (
-		start int = int(advanceBytes)
+		start int
 		next  int
 	)
 	start += 1 // ignore encode byte
-	if next = bytes.IndexByte(fileStream[start:], byte(0)); next == -1 {
-		log.Fatal("invalid image content in apic")
+	if next = bytes.IndexByte(content[start:], byte(0)); next == -1 || start+next+2 > len(content) {
+		return nil, ErrInvalidCover
 	}
 	start += next + 1
 	start += 1 // ignore image type
-	if next = bytes.IndexByte(fileStream[start:], byte(0)); next == -1 {
-		log.Fatal("invalid image content in apic")
+	if next = bytes.IndexByte(content[start:], byte(0)); next == -1 {
+		return nil, ErrInvalidCover
 	}
 	start += next + 1
-	imageBytes := fileStream[start : advanceBytes+frameSize]
+	imageBytes := content[start:]
 	image, _, err := image.Decode(bytes.NewReader(imageBytes))
 	if err != nil {
-		log.Fatal("invalid binary content in apic:", err)
+		return nil, errors.Join(ErrInvalidCover, err)
+	}
+	return image, nil
+}
+
+func readAlbum(audioPath string) (Album, error) {
+	if regexp.MustCompile(`\.(flac)$`).MatchString(audioPath) {
+		f, err := os.Open(audioPath)
+		if err != nil {
+			return Album{}, err
+		}
+		defer f.Close()
+		return parseFLAC(f, true)
+	}
+	fileStream, err := os.ReadFile(audioPath)
+	if err != nil {
+		return Album{}, err
+	}
+	return parse(fileStream)
+}
+
+// readTag read only the id3 tag instead of the whole audio, only the header is returned when there is no tag
+func readTag(r io.Reader) ([]byte, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:3]) != "ID3" {
+		return header, nil
+	}
+	size := synchsafe(header[6:10])
+	fileStream := make([]byte, 10+size)
+	copy(fileStream, header)
+	if _, err := io.ReadFull(r, fileStream[10:10+size]); err != nil {
+		return nil, err
+	}
+	return fileStream, nil
+}
+
+// readTrackNumber only read the tag of the audio, disc and track are 0 when not tagged
+func readTrackNumber(audioPath string) (disc, track int, err error) {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	var album Album
+	if regexp.MustCompile(`\.(flac)$`).MatchString(audioPath) {
+		album, err = parseFLAC(f, false)
+	} else {
+		fileStream, err := readTag(f)
+		if err != nil {
+			return 0, 0, err
+		}
+		album, err = parseTag(fileStream, false)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return album.Disc, album.Track, err
+}
+
+// readTags read the tags without cover of a mp3 or flac, other formats have none
+func readTags(audioPath string) (Album, error) {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return Album{}, err
+	}
+	defer f.Close()
+	switch {
+	case regexp.MustCompile(`\.(flac)$`).MatchString(audioPath):
+		return parseFLAC(f, false)
+	case regexp.MustCompile(`\.(mp3)$`).MatchString(audioPath):
+		fileStream, err := readTag(f)
+		if err != nil {
+			return Album{}, err
+		}
+		return parseTag(fileStream, false)
 	}
-	return image
+	return Album{}, nil
 }
 
 func isValidAudio(s song) error {
-	accpetFormat := regexp.MustCompile(`\.(mp3|wav)$`)
+	accpetFormat := regexp.MustCompile(`\.(mp3|wav|flac)$`)
 	if !accpetFormat.MatchString(s.name) {
-		return errors.New("only support mp3 format audio")
+		return errors.New("only support mp3, wav and flac format audio")
 	}
 	mp3Format := regexp.MustCompile(`\.(mp3)$`)
 	// only validate mp3 format
@@ -154,11 +358,12 @@ func isValidAudio(s song) error {
 		if err != nil {
 			return err
 		}
-		fileStream, err := io.ReadAll(f)
+		defer f.Close()
+		fileStream, err := readTag(f)
 		if err != nil {
 			return err
 		}
-		if _, err := parse(fileStream); err != nil {
+		if _, err := parseTag(fileStream, false); err != nil {
 			return err
 		}
 	}
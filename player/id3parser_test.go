@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -53,6 +54,22 @@ func TestParse(t *testing.T) {
 		assert.EqualError(t, err, ErrInvalidTagHeaderSize.Error())
 	})
 
+	t.Run("id3v2.4 synchsafe frame size", func(t *testing.T) {
+		title := strings.Repeat("a", 200)
+		frames := "TIT2" + string(synchsafeBytes(uint32(len(title)+1))) + "\x00\x00\x03" + title + "TPE1\x00\x00\x00\x00\x00\x00"
+		album, err := parse([]byte("ID3\x04\x00\x00" + string(synchsafeBytes(uint32(len(frames)))) + frames))
+		assert.NoError(t, err)
+		assert.Equal(t, title, album.Title)
+		assert.Equal(t, "Unknown Artist", album.Artist, "empty frame")
+	})
+	t.Run("frame past the tag", func(t *testing.T) {
+		frames := "TIT2\x00\x00\x01\x00\x00\x00\x00title"
+		_, err := parse([]byte("ID3\x03\x00\x00" + string(synchsafeBytes(uint32(len(frames)))) + frames))
+		assert.ErrorIs(t, err, ErrInvalidFrameSize)
+		_, err = parse([]byte("ID3"))
+		assert.ErrorIs(t, err, ErrInvalidTagHeaderIdentifier)
+	})
+
 	t.Run("get album artist", func(t *testing.T) {
 		album, err := parse(static.NoCoverMP3Bytes)
 		assert.Equal(t, ExpectedArtist, album.Artist)
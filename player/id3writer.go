@@ -0,0 +1,103 @@
+package player
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tagPadding is the room left after the frames of a grown tag, the readers stop at its zero bytes
+const tagPadding = 1024
+
+// writeReplayGainTags replace the REPLAYGAIN_* TXXX frames of the gains in rg, adding a tag when it has none.
+// The other frames are kept as they are, as is the size of the tag when they fit. The file is replaced once it is completely written
+func writeReplayGainTags(path string, rg ReplayGain) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	version := byte(3)
+	tagSize := 0 // of the tag replaced
+	var frames []byte
+	audio := data
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		version = data[3]
+		if version != 3 && version != 4 {
+			return ErrInvalidTagHeaderVersion
+		}
+		if data[5] != 0 { // unsynchronisation, extended header and footer are not rewritten
+			return ErrInvalidTagHeaderflags
+		}
+		size := int(synchsafe(data[6:10]))
+		if 10+size > len(data) {
+			return ErrInvalidTagHeaderSize
+		}
+		tag := data[10 : 10+size]
+		audio, tagSize = data[10+size:], size
+		for {
+			id, frameSize, err := frameHeader(tag, version)
+			if err != nil {
+				return err
+			}
+			if id == "" {
+				break
+			}
+			frame := tag[:10+frameSize]
+			tag = tag[10+frameSize:]
+			if id == "TXXX" {
+				description, _ := parseUserText(frame[10:])
+				description = strings.ToUpper(description)
+				if rg.HasTrackGain && strings.HasPrefix(description, "REPLAYGAIN_TRACK_") || rg.HasAlbumGain && strings.HasPrefix(description, "REPLAYGAIN_ALBUM_") {
+					continue
+				}
+			}
+			frames = append(frames, frame...)
+		}
+	}
+	gain := func(db float64) string { return strconv.FormatFloat(db, 'f', 2, 64) + " dB" }
+	peak := func(p float64) string { return strconv.FormatFloat(p, 'f', 6, 64) }
+	if rg.HasTrackGain {
+		frames = appendUserText(frames, version, "REPLAYGAIN_TRACK_GAIN", gain(rg.TrackGain))
+		frames = appendUserText(frames, version, "REPLAYGAIN_TRACK_PEAK", peak(rg.TrackPeak))
+	}
+	if rg.HasAlbumGain {
+		frames = appendUserText(frames, version, "REPLAYGAIN_ALBUM_GAIN", gain(rg.AlbumGain))
+		frames = appendUserText(frames, version, "REPLAYGAIN_ALBUM_PEAK", peak(rg.AlbumPeak))
+	}
+	if len(frames) > tagSize {
+		tagSize = len(frames) + tagPadding
+	}
+	rewritten := append([]byte{'I', 'D', '3', version, 0, 0}, synchsafeBytes(uint32(tagSize))...)
+	rewritten = append(append(rewritten, frames...), make([]byte, tagSize-len(frames))...)
+	rewritten = append(rewritten, audio...)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", rewritten, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// appendUserText append a latin-1 TXXX frame, its size is synchsafe in id3v2.4
+func appendUserText(frames []byte, version byte, description, value string) []byte {
+	content := "\x00" + description + "\x00" + value
+	size := binary.BigEndian.AppendUint32(nil, uint32(len(content)))
+	if version == 4 {
+		size = synchsafeBytes(uint32(len(content)))
+	}
+	frames = append(frames, "TXXX"...)
+	frames = append(frames, size...)
+	frames = append(frames, 0, 0) // flags
+	return append(frames, content...)
+}
+
+func synchsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+func synchsafeBytes(n uint32) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
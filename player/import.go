@@ -0,0 +1,279 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const importWorkers = 4
+
+type importResult struct {
+	item  playlistItem
+	disc  int
+	track int
+	err   error
+}
+
+// collectDir list the importable items under root, audio described by a cue sheet is imported as its virtual tracks instead of the whole file
+func collectDir(ctx context.Context, root string, options scanOptions) ([]playlistItem, []string, error) {
+	files, err := scanDir(ctx, root, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		items    []playlistItem
+		rejected []string
+		sheeted  = map[string]bool{}
+	)
+	for _, file := range files {
+		if filepath.Ext(file) != ".cue" {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		sheet, err := readCUE(f, filepath.Dir(file))
+		f.Close()
+		if err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		for _, item := range sheet {
+			sheeted[item.path] = true
+		}
+		items = append(items, sheet...)
+	}
+	audioFormat := regexp.MustCompile(`\.(mp3|wav|flac)$`)
+	for _, file := range files {
+		if !sheeted[file] && audioFormat.MatchString(file) {
+			items = append(items, playlistItem{path: file, duration: -1})
+		}
+	}
+	return items, rejected, nil
+}
+
+// collectDropped list the importable items of the dropped files and directories in the dropped order, items of a directory are sorted by disc and track within it
+func collectDropped(ctx context.Context, paths []string, options scanOptions) ([]playlistItem, []string, error) {
+	var (
+		items    []playlistItem
+		rejected []string
+	)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: missing", path))
+			continue
+		}
+		switch {
+		case info.IsDir():
+			dirItems, dirRejected, err := collectDir(ctx, path, options)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, err
+				}
+				rejected = append(rejected, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			sortTracks(dirItems, func(item playlistItem) string {
+				return item.path
+			}, func(item playlistItem) (int, int) {
+				disc, track, _ := readTrackNumber(item.path)
+				return disc, track
+			})
+			items = append(items, dirItems...)
+			rejected = append(rejected, dirRejected...)
+		case filepath.Ext(path) == ".cue":
+			f, err := os.Open(path)
+			if err != nil {
+				rejected = append(rejected, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			sheet, err := readCUE(f, filepath.Dir(path))
+			f.Close()
+			if err != nil {
+				rejected = append(rejected, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			items = append(items, sheet...)
+		default:
+			items = append(items, playlistItem{path: path, duration: -1})
+		}
+	}
+	return items, rejected, nil
+}
+
+// validateItems check the items on bounded workers, keeping their order, disc and track are only read when sorted.
+// report is called after every checked item, item not checked because of cancel has context.Canceled as error
+func validateItems(ctx context.Context, items []playlistItem, sorted bool, report func(checked, accepted int)) []importResult {
+	var (
+		results  = make([]importResult, len(items))
+		indexes  = make(chan int)
+		checked  atomic.Int64
+		accepted atomic.Int64
+		wg       sync.WaitGroup
+	)
+	for i := range results {
+		results[i] = importResult{item: items[i], err: context.Canceled}
+	}
+	for range importWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := importResult{item: items[i], err: checkItem(items[i])}
+				if result.err == nil {
+					accepted.Add(1)
+					if sorted {
+						result.disc, result.track, _ = readTrackNumber(items[i].path)
+					}
+				}
+				results[i] = result
+				report(int(checked.Add(1)), int(accepted.Load()))
+			}
+		}()
+	}
+feed:
+	for i := range items {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func checkItem(item playlistItem) error {
+	if _, err := os.Stat(item.path); err != nil {
+		return fmt.Errorf("missing")
+	}
+	return isValidAudio(song{path: item.path, name: filepath.Base(item.path)})
+}
+
+// startImport collect and validate the items in background with a cancelable progress dialog, the accepted items are inserted at index of the playlist.
+// When replace, the accepted items replace the songs instead, and a cancelled import leave the playlist as it was
+func (p *Player) startImport(pl *Playlist, title string, at int, sorted, replace bool, collect func(ctx context.Context) ([]playlistItem, []string, error)) {
+	if pl.smart != nil {
+		dialog.ShowInformation(title, "Smart playlist is filled by its rules, edit the rules to change its songs", p.window)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	status := widget.NewLabel("Scanning...")
+	progress := widget.NewProgressBar()
+	var cancelBtn *widget.Button
+	cancelBtn = widget.NewButton("Cancel", func() {
+		cancel()
+		cancelBtn.Disable()
+	})
+	progressDialog := dialog.NewCustomWithoutButtons(title, container.NewVBox(status, progress, container.NewCenter(cancelBtn)), p.window)
+	progressDialog.Resize(fyne.NewSize(p.window.Canvas().Size().Width*0.4, progressDialog.MinSize().Height))
+	progressDialog.Show()
+	go func() {
+		defer cancel()
+		items, rejected, err := collect(ctx)
+		if err != nil {
+			fyne.Do(func() {
+				progressDialog.Hide()
+				if ctx.Err() == nil {
+					dialog.ShowError(err, p.window)
+				}
+			})
+			return
+		}
+		fyne.Do(func() {
+			progress.Max = float64(len(items))
+			status.SetText(fmt.Sprintf("Scanned 0 of %d, added 0", len(items)))
+		})
+		results := validateItems(ctx, items, sorted, func(checked, accepted int) {
+			fyne.Do(func() {
+				progress.SetValue(float64(checked))
+				status.SetText(fmt.Sprintf("Scanned %d of %d, added %d", checked, len(items), accepted))
+			})
+		})
+		results = slices.DeleteFunc(results, func(result importResult) bool {
+			if result.err != nil && result.err != context.Canceled {
+				rejected = append(rejected, fmt.Sprintf("%s: %v", result.item.path, result.err))
+			}
+			return result.err != nil
+		})
+		if sorted {
+			sortTracks(results, func(result importResult) string {
+				return result.item.path
+			}, func(result importResult) (int, int) {
+				return result.disc, result.track
+			})
+		}
+		var songs []song
+		for _, result := range results {
+			songs = append(songs, itemSong(result.item))
+		}
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if replace && ctx.Err() != nil {
+				songs = nil
+			} else if replace {
+				pl.clear()
+				if p.Playlist == pl {
+					p.UI.PrevBtn.Disable()
+					p.UI.NextBtn.Disable()
+				}
+				p.refreshPlaylist(pl)
+			}
+			added := pl.insert(min(at, len(pl.songs)), songs)
+			if added > 0 {
+				p.refreshPlaylist(pl)
+				p.updateSkipButtons()
+			}
+			summary := fmt.Sprintf("Scanned %d, added %d, %d already in playlist", len(items), added, len(songs)-added)
+			if ctx.Err() != nil {
+				summary = "Cancelled. " + summary
+			}
+			if len(rejected) == 0 {
+				if added == 0 {
+					dialog.ShowInformation(title, summary, p.window)
+				}
+				return
+			}
+			detail := widget.NewLabel(strings.Join(rejected, "\n"))
+			summaryDialog := dialog.NewCustom(title, "OK", container.NewBorder(
+				widget.NewLabel(fmt.Sprintf("%s, %d rejected:", summary, len(rejected))),
+				nil,
+				nil,
+				nil,
+				container.NewScroll(detail),
+			), p.window)
+			windowSize := p.window.Canvas().Size()
+			summaryDialog.Resize(fyne.NewSize(windowSize.Width*0.6, windowSize.Height*0.6))
+			summaryDialog.Show()
+		})
+	}()
+}
+
+// importItems validate the items already read from a playlist file and append them to the playlist
+func (p *Player) importItems(pl *Playlist, title string, items []playlistItem) {
+	p.startImport(pl, title, len(pl.songs), false, false, func(context.Context) ([]playlistItem, []string, error) {
+		return items, nil, nil
+	})
+}
+
+// replaceItems validate the items and replace the songs of the playlist with them, once validated
+func (p *Player) replaceItems(pl *Playlist, title string, items []playlistItem) {
+	p.startImport(pl, title, 0, false, true, func(context.Context) ([]playlistItem, []string, error) {
+		return items, nil, nil
+	})
+}
@@ -0,0 +1,94 @@
+package player
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"b/02.wav", "b/01.wav", "a.wav", "cover.jpg"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+	sheet := "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 01 01:00:00\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.cue"), []byte(sheet), 0644))
+
+	t.Run("collect dir", func(t *testing.T) {
+		items, rejected, err := collectDir(context.Background(), dir, scanOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, rejected)
+		var paths []string
+		for _, item := range items {
+			paths = append(paths, item.path)
+		}
+		a := filepath.Join(dir, "a.wav")
+		assert.Equal(t, []string{a, a, filepath.Join(dir, "b", "01.wav"), filepath.Join(dir, "b", "02.wav")}, paths)
+	})
+	t.Run("cancelled collect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := collectDir(ctx, dir, scanOptions{})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("collect dropped", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.wav")
+		items, rejected, err := collectDropped(context.Background(), []string{filepath.Join(dir, "b"), filepath.Join(dir, "a.cue"), missing}, scanOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{missing + ": missing"}, rejected)
+		var paths []string
+		for _, item := range items {
+			paths = append(paths, filepath.Base(item.path))
+		}
+		assert.Equal(t, []string{"01.wav", "02.wav", "a.wav", "a.wav"}, paths)
+
+		items, _, err = collectDropped(context.Background(), []string{filepath.Join(dir, "b", "02.wav"), filepath.Join(dir, "b")}, scanOptions{})
+		assert.NoError(t, err)
+		paths = nil
+		for _, item := range items {
+			paths = append(paths, filepath.Base(item.path))
+		}
+		assert.Equal(t, []string{"02.wav", "01.wav", "02.wav"}, paths, "dropped order kept, directory sorted within")
+	})
+	t.Run("report missing entries", func(t *testing.T) {
+		exist := filepath.Join(dir, "a.wav")
+		missing := filepath.Join(dir, "missing.wav")
+		var checked atomic.Int64
+		results := validateItems(context.Background(), []playlistItem{
+			{path: exist, title: "Exist", duration: -1},
+			{path: missing, duration: -1},
+			{path: filepath.Join(dir, "cover.jpg"), duration: -1},
+		}, false, func(int, int) { checked.Add(1) })
+		assert.Equal(t, int64(3), checked.Load())
+		assert.NoError(t, results[0].err)
+		assert.EqualError(t, results[1].err, "missing")
+		assert.Error(t, results[2].err)
+		assert.Equal(t, song{path: exist, name: "Exist", title: "Exist"}, itemSong(results[0].item))
+	})
+	t.Run("cancelled validate", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results := validateItems(ctx, make([]playlistItem, 100), false, func(int, int) {})
+		cancelled := 0
+		for _, result := range results {
+			if result.err == context.Canceled {
+				cancelled++
+			}
+		}
+		assert.Greater(t, cancelled, 0)
+	})
+	t.Run("insert keep playing song", func(t *testing.T) {
+		pl := Playlist{songs: []song{{path: "a"}, {path: "b"}}, playingIndex: 1}
+		added := pl.insert(1, []song{{path: "c"}, {path: "a"}, {path: "c"}, {path: "d"}})
+		assert.Equal(t, 2, added)
+		assert.Equal(t, []song{{path: "a"}, {path: "c"}, {path: "d"}, {path: "b"}}, pl.songs)
+		assert.Equal(t, 3, pl.playingIndex)
+		pl.insert(4, []song{{path: "e"}})
+		assert.Equal(t, 3, pl.playingIndex)
+	})
+}
@@ -0,0 +1,210 @@
+package player
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type libraryTrack struct {
+	Path     string        `json:"path"`
+	ModTime  time.Time     `json:"modTime"`
+	Size     int64         `json:"size"`
+	Title    string        `json:"title,omitempty"`
+	Artist   string        `json:"artist,omitempty"`
+	Album    string        `json:"album,omitempty"`
+	Genre    string        `json:"genre,omitempty"`
+	Year     int           `json:"year,omitempty"`
+	Track    int           `json:"track,omitempty"`
+	Disc     int           `json:"disc,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Added    time.Time     `json:"added"` // first time the file is indexed
+	// measured by the loudness scan, kept until the file change
+	Loudness   float64     `json:"loudness,omitempty"` // integrated loudness in LUFS
+	ReplayGain *ReplayGain `json:"replayGain,omitempty"`
+}
+
+func (t libraryTrack) metadata() metadata {
+	return metadata{title: t.Title, artist: t.Artist, album: t.Album, genre: t.Genre, year: t.Year, track: t.Track, disc: t.Disc, duration: t.Duration}
+}
+
+type storedLibrary struct {
+	Folders []string       `json:"folders"`
+	Tracks  []libraryTrack `json:"tracks"`
+}
+
+func libraryFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "library.json"), nil
+}
+
+// loadLibrary return an empty library when it is never saved
+func loadLibrary() (folders []string, tracks map[string]libraryTrack, err error) {
+	tracks = map[string]libraryTrack{}
+	file, err := libraryFile()
+	if err != nil {
+		return nil, tracks, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, tracks, nil
+	}
+	if err != nil {
+		return nil, tracks, err
+	}
+	var stored storedLibrary
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, tracks, err
+	}
+	for _, track := range stored.Tracks {
+		tracks[track.Path] = track
+	}
+	return stored.Folders, tracks, nil
+}
+
+// saveLibrary write to a temporary file first, so a crash while saving does not lose the library
+func saveLibrary(folders []string, tracks map[string]libraryTrack) error {
+	file, err := libraryFile()
+	if err != nil {
+		return err
+	}
+	stored := storedLibrary{Folders: folders, Tracks: []libraryTrack{}}
+	for _, track := range tracks {
+		stored.Tracks = append(stored.Tracks, track)
+	}
+	slices.SortFunc(stored.Tracks, func(a, b libraryTrack) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// scanLibrary index the audio under the folders, file with unchanged mtime and size keep its old entry instead of being read again.
+// Tracks of the folder unable to be scanned are kept, since it is likely an unmounted drive
+func scanLibrary(ctx context.Context, folders []string, old map[string]libraryTrack, read func(path string) (metadata, error), report func(scanned int)) (map[string]libraryTrack, error) {
+	audioFormat := regexp.MustCompile(`\.(mp3|wav|flac)$`)
+	tracks := map[string]libraryTrack{}
+	scanned := 0
+	for _, folder := range folders {
+		files, err := scanDir(ctx, folder, scanOptions{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			for path, track := range old {
+				if inFolder(path, folder) {
+					tracks[path] = track
+				}
+			}
+			continue
+		}
+		for _, file := range files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if !audioFormat.MatchString(file) {
+				continue
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			track, exist := old[file]
+			if !exist || !track.ModTime.Equal(info.ModTime()) || track.Size != info.Size() {
+				m, _ := read(file) // unparsable tags still leave the file browsable by its name
+				added := time.Now()
+				if exist {
+					added = track.Added
+				}
+				track = libraryTrack{
+					Path:     file,
+					ModTime:  info.ModTime(),
+					Size:     info.Size(),
+					Title:    m.title,
+					Artist:   m.artist,
+					Album:    m.album,
+					Genre:    m.genre,
+					Year:     m.year,
+					Track:    m.track,
+					Disc:     m.disc,
+					Duration: m.duration,
+					Added:    added,
+				}
+			}
+			tracks[file] = track
+			scanned++
+			report(scanned)
+		}
+	}
+	return tracks, nil
+}
+
+func inFolder(path, folder string) bool {
+	rel, err := filepath.Rel(folder, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+type libraryAlbum struct {
+	title  string
+	tracks []libraryTrack
+}
+
+type libraryArtist struct {
+	name   string
+	albums []libraryAlbum
+}
+
+// groupLibrary group the tracks by artist then album, tracks of an album are in disc and track order
+func groupLibrary(tracks map[string]libraryTrack) []libraryArtist {
+	grouped := map[string]map[string][]libraryTrack{}
+	for _, track := range tracks {
+		artist, album := track.Artist, track.Album
+		if artist == "" {
+			artist = "Unknown Artist"
+		}
+		if album == "" {
+			album = "Unknown Album"
+		}
+		if grouped[artist] == nil {
+			grouped[artist] = map[string][]libraryTrack{}
+		}
+		grouped[artist][album] = append(grouped[artist][album], track)
+	}
+	var artists []libraryArtist
+	for name, albums := range grouped {
+		artist := libraryArtist{name: name}
+		for title, tracks := range albums {
+			slices.SortFunc(tracks, func(a, b libraryTrack) int {
+				return cmp.Or(cmp.Compare(a.Disc, b.Disc), cmp.Compare(a.Track, b.Track), naturalCompare(filepath.Base(a.Path), filepath.Base(b.Path)))
+			})
+			artist.albums = append(artist.albums, libraryAlbum{title, tracks})
+		}
+		slices.SortFunc(artist.albums, func(a, b libraryAlbum) int {
+			return naturalCompare(a.title, b.title)
+		})
+		artists = append(artists, artist)
+	}
+	slices.SortFunc(artists, func(a, b libraryArtist) int {
+		return naturalCompare(a.name, b.name)
+	})
+	return artists
+}
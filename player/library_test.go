@@ -0,0 +1,112 @@
+package player
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLibrary(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	for _, file := range []string{"A/x/01.mp3", "A/x/02.mp3", "B/03.flac", "B/cover.jpg"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(file), 0644))
+	}
+	var reads []string
+	read := func(path string) (metadata, error) {
+		reads = append(reads, filepath.Base(path))
+		return metadata{title: filepath.Base(path), artist: filepath.Base(filepath.Dir(path))}, nil
+	}
+	folders := []string{filepath.Join(dir, "A"), filepath.Join(dir, "B")}
+
+	tracks, err := scanLibrary(context.Background(), folders, map[string]libraryTrack{}, read, func(int) {})
+	assert.NoError(t, err)
+	assert.Len(t, tracks, 3)
+	assert.ElementsMatch(t, []string{"01.mp3", "02.mp3", "03.flac"}, reads)
+
+	t.Run("save and load", func(t *testing.T) {
+		assert.NoError(t, saveLibrary(folders, tracks))
+		gotFolders, gotTracks, err := loadLibrary()
+		assert.NoError(t, err)
+		assert.Equal(t, folders, gotFolders)
+		assert.Len(t, gotTracks, 3)
+		for path, track := range tracks {
+			assert.True(t, track.ModTime.Equal(gotTracks[path].ModTime))
+			assert.Equal(t, track.Title, gotTracks[path].Title)
+		}
+	})
+	t.Run("incremental rescan", func(t *testing.T) {
+		reads = nil
+		changed := filepath.Join(dir, "A", "x", "02.mp3")
+		assert.NoError(t, os.WriteFile(changed, []byte("changed"), 0644))
+		assert.NoError(t, os.Remove(filepath.Join(dir, "B", "03.flac")))
+		rescanned, err := scanLibrary(context.Background(), folders, tracks, read, func(int) {})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"02.mp3"}, reads)
+		assert.Len(t, rescanned, 2)
+		assert.Equal(t, int64(len("changed")), rescanned[changed].Size)
+	})
+	t.Run("keep tracks of missing folder", func(t *testing.T) {
+		reads = nil
+		missing := filepath.Join(dir, "unmounted")
+		old := map[string]libraryTrack{filepath.Join(missing, "a.mp3"): {Path: filepath.Join(missing, "a.mp3")}}
+		rescanned, err := scanLibrary(context.Background(), []string{missing}, old, read, func(int) {})
+		assert.NoError(t, err)
+		assert.Equal(t, old, rescanned)
+		assert.Empty(t, reads)
+	})
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := scanLibrary(ctx, folders, tracks, read, func(int) {})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("in folder", func(t *testing.T) {
+		assert.True(t, inFolder(filepath.FromSlash("/music/a/b.mp3"), filepath.FromSlash("/music")))
+		assert.False(t, inFolder(filepath.FromSlash("/musicx/b.mp3"), filepath.FromSlash("/music")))
+		assert.False(t, inFolder(filepath.FromSlash("/other/..b.mp3"), filepath.FromSlash("/music")))
+	})
+}
+
+func TestLibraryTree(t *testing.T) {
+	tracks := map[string]libraryTrack{
+		"/m/2.mp3": {Path: "/m/2.mp3", Title: "Second", Artist: "Band", Album: "Debut", Track: 2},
+		"/m/1.mp3": {Path: "/m/1.mp3", Title: "First", Artist: "Band", Album: "Debut", Track: 1},
+		"/m/b.mp3": {Path: "/m/b.mp3", Artist: "band 2", Album: "Live", ModTime: time.Unix(0, 0)},
+		"/m/u.mp3": {Path: "/m/u.mp3"},
+	}
+	artists := groupLibrary(tracks)
+	var names []string
+	for _, artist := range artists {
+		names = append(names, artist.name)
+	}
+	assert.Equal(t, []string{"Band", "band 2", "Unknown Artist"}, names)
+
+	nodes, children := libraryTree(artists)
+	assert.Equal(t, []string{"Band", "band 2", "Unknown Artist"}, children[""])
+	assert.Equal(t, []string{"Band\x00Debut"}, children["Band"])
+	assert.Equal(t, []string{"Band\x00Debut\x00/m/1.mp3", "Band\x00Debut\x00/m/2.mp3"}, children["Band\x00Debut"])
+	assert.Equal(t, "01  First", nodes["Band\x00Debut\x00/m/1.mp3"].label)
+	assert.Equal(t, "/m/1.mp3", nodes["Band\x00Debut"].cover)
+	assert.Len(t, nodes["Band"].tracks, 2)
+	assert.Equal(t, "b", nodes["band 2\x00Live\x00/m/b.mp3"].label)
+	assert.Equal(t, "Unknown Album", nodes["Unknown Artist\x00Unknown Album"].label)
+
+	t.Run("grid albums", func(t *testing.T) {
+		albums := gridAlbums(artists)
+		assert.Len(t, albums, 3)
+		assert.Equal(t, "Band", albums[0].artist)
+		assert.Equal(t, "Debut", albums[0].title)
+		assert.Equal(t, "/m/1.mp3", albums[0].tracks[0].Path)
+	})
+	t.Run("thumbnail keep aspect ratio", func(t *testing.T) {
+		assert.Equal(t, image.Rect(0, 0, 64, 32), thumbnail(image.NewRGBA(image.Rect(0, 0, 200, 100)), 64).Bounds())
+		assert.Equal(t, image.Rect(0, 0, 16, 64), thumbnail(image.NewRGBA(image.Rect(0, 0, 25, 100)), 64).Bounds())
+	})
+}
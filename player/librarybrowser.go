@@ -0,0 +1,271 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// libraryNode is an artist, album or track of the library tree
+type libraryNode struct {
+	label  string
+	cover  string // audio to read the album cover from, empty when the node has no cover
+	tracks []libraryTrack
+}
+
+// libraryTree build the tree nodes keyed by id, artist id is its name, album and track id append their album and path after a NUL
+func libraryTree(artists []libraryArtist) (nodes map[string]libraryNode, children map[string][]string) {
+	nodes = map[string]libraryNode{}
+	children = map[string][]string{"": {}}
+	for _, artist := range artists {
+		artistID := artist.name
+		children[""] = append(children[""], artistID)
+		children[artistID] = []string{}
+		var artistTracks []libraryTrack
+		for _, album := range artist.albums {
+			albumID := artistID + "\x00" + album.title
+			children[artistID] = append(children[artistID], albumID)
+			children[albumID] = []string{}
+			for _, track := range album.tracks {
+				trackID := albumID + "\x00" + track.Path
+				children[albumID] = append(children[albumID], trackID)
+				label := track.Title
+				if label == "" {
+					label = strings.TrimSuffix(filepath.Base(track.Path), filepath.Ext(track.Path))
+				}
+				if track.Track > 0 {
+					label = fmt.Sprintf("%02d  %s", track.Track, label)
+				}
+				nodes[trackID] = libraryNode{label: label, tracks: []libraryTrack{track}}
+			}
+			nodes[albumID] = libraryNode{label: album.title, cover: album.tracks[0].Path, tracks: album.tracks}
+			artistTracks = append(artistTracks, album.tracks...)
+		}
+		nodes[artistID] = libraryNode{label: artist.name, tracks: artistTracks}
+	}
+	return nodes, children
+}
+
+func (p *Player) newLibraryBrowser() {
+	p.covers = newLazyCache(readCachedThumbnail, func() {
+		fyne.Do(func() {
+			p.UI.Library.Refresh()
+			p.UI.AlbumGrid.Refresh()
+		})
+	})
+	p.UI.Library = widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			return p.library.children[id]
+		},
+		func(id widget.TreeNodeID) bool {
+			_, branch := p.library.children[id]
+			return branch
+		},
+		func(branch bool) fyne.CanvasObject {
+			cover := canvas.NewImageFromImage(nil)
+			cover.FillMode = canvas.ImageFillContain
+			cover.SetMinSize(fyne.NewSquareSize(32))
+			label := widget.NewLabel("libraryNode")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, cover, nil, label)
+		},
+		func(id widget.TreeNodeID, branch bool, o fyne.CanvasObject) {
+			node := p.library.nodes[id]
+			box := o.(*fyne.Container)
+			label := box.Objects[0].(*widget.Label)
+			cover := box.Objects[1].(*canvas.Image)
+			label.SetText(node.label)
+			if node.cover == "" {
+				cover.Hide()
+				return
+			}
+			cover.Image, _ = p.covers.get(node.cover)
+			cover.Show()
+			cover.Refresh()
+		})
+	var selected widget.TreeNodeID
+	p.UI.Library.OnSelected = func(id widget.TreeNodeID) {
+		selected = id
+		p.UI.LibraryAddBtn.Enable()
+		p.UI.LibraryLoudnessBtn.Enable()
+	}
+	p.UI.Library.OnUnselected = func(widget.TreeNodeID) {
+		selected = ""
+		p.UI.LibraryAddBtn.Disable()
+		if p.library.measure == nil { // still cancel the running measure
+			p.UI.LibraryLoudnessBtn.Disable()
+		}
+	}
+	p.UI.LibraryAddBtn = widget.NewButtonWithIcon("add", theme.ContentAddIcon(), func() {
+		index := slices.IndexFunc(p.playlists, func(pl *Playlist) bool {
+			return pl.tab == p.UI.Playlists.Selected()
+		})
+		node, exist := p.library.nodes[selected]
+		if index == -1 || !exist {
+			return
+		}
+		var items []playlistItem
+		for _, track := range node.tracks {
+			items = append(items, playlistItem{path: track.Path, duration: -1})
+		}
+		p.importItems(p.playlists[index], "Add "+node.label, items)
+	})
+	p.UI.LibraryAddBtn.Disable()
+	p.UI.LibraryLoudnessBtn = widget.NewButtonWithIcon("loudness", theme.VolumeUpIcon(), func() {
+		if p.library.measure != nil {
+			p.library.measure()
+			return
+		}
+		if node, exist := p.library.nodes[selected]; exist {
+			p.measureLoudness(node)
+		}
+	})
+	p.UI.LibraryLoudnessBtn.Disable()
+	p.UI.LibraryFoldersBtn = widget.NewButtonWithIcon("folders", theme.FolderIcon(), p.showLibraryFolders)
+	p.UI.LibraryRescanBtn = widget.NewButtonWithIcon("rescan", theme.ViewRefreshIcon(), p.rescanLibrary)
+	p.UI.LibraryStatus = widget.NewLabel("")
+	p.UI.LibraryStatus.Truncation = fyne.TextTruncateEllipsis
+	p.newAlbumGrid()
+
+	folders, tracks, err := loadLibrary()
+	if err != nil {
+		log.Println("loadLibrary", err)
+	}
+	p.library.folders = folders
+	p.setLibraryTracks(tracks)
+	if len(folders) > 0 {
+		p.rescanLibrary()
+	}
+	p.watchLibrary()
+}
+
+// setLibraryTracks replace the tracks of the library, the playlists reuse their tags instead of reading the files again
+func (p *Player) setLibraryTracks(tracks map[string]libraryTrack) {
+	p.library.tracks = tracks
+	artists := groupLibrary(tracks)
+	p.library.nodes, p.library.children = libraryTree(artists)
+	p.library.albums = gridAlbums(artists)
+	for _, track := range tracks {
+		p.metadata.put(track.Path, track.metadata())
+	}
+	for _, pl := range p.playlists {
+		if pl.smart != nil {
+			p.setSmart(pl, pl.smart)
+		}
+	}
+	p.applyReplayGain()
+	p.UI.LibraryStatus.SetText(fmt.Sprintf("%d tracks", len(tracks)))
+	p.UI.Library.Refresh()
+	p.UI.AlbumGrid.Refresh()
+}
+
+// rescanLibrary index the library folders in background, only changed files are read again
+func (p *Player) rescanLibrary() {
+	if p.library.cancel != nil {
+		p.library.rescan = true
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.library.cancel = cancel
+	p.UI.LibraryRescanBtn.Disable()
+	p.UI.LibraryStatus.SetText("Scanning...")
+	folders, old := p.library.folders, p.library.tracks
+	go func() {
+		defer cancel()
+		tracks, err := scanLibrary(ctx, folders, old, readMetadata, func(scanned int) {
+			if scanned%50 == 0 {
+				fyne.Do(func() {
+					p.UI.LibraryStatus.SetText(fmt.Sprintf("Scanning... %d tracks", scanned))
+				})
+			}
+		})
+		fyne.Do(func() {
+			p.library.cancel = nil
+			p.UI.LibraryRescanBtn.Enable()
+			if err != nil {
+				p.UI.LibraryStatus.SetText("Scan cancelled")
+				return
+			}
+			if p.library.rescan || !slices.Equal(folders, p.library.folders) { // changed while scanning
+				p.library.rescan = false
+				p.rescanLibrary()
+				return
+			}
+			moves := detectMoves(p.library.tracks, tracks)
+			carryMoves(moves, p.library.tracks, tracks)
+			carryLoudness(p.library.tracks, tracks)
+			p.setLibraryTracks(tracks)
+			p.relocatePlaylists(moves)
+			p.relocateHistory(moves)
+			if err := saveLibrary(p.library.folders, tracks); err != nil {
+				dialog.ShowError(err, p.window)
+			}
+		})
+	}()
+}
+
+func (p *Player) showLibraryFolders() {
+	var folderList *widget.List
+	folderList = widget.NewList(
+		func() int {
+			return len(p.library.folders)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.DeleteIcon(), nil), widget.NewLabel("libraryFolder"))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			box := o.(*fyne.Container)
+			box.Objects[0].(*widget.Label).SetText(p.library.folders[i])
+			box.Objects[1].(*widget.Button).OnTapped = func() {
+				folder := p.library.folders[i]
+				p.library.folders = slices.Delete(slices.Clone(p.library.folders), i, i+1)
+				tracks := maps.Clone(p.library.tracks)
+				maps.DeleteFunc(tracks, func(path string, _ libraryTrack) bool {
+					return inFolder(path, folder) && !slices.ContainsFunc(p.library.folders, func(other string) bool {
+						return inFolder(path, other)
+					})
+				})
+				p.setLibraryTracks(tracks)
+				if err := saveLibrary(p.library.folders, tracks); err != nil {
+					dialog.ShowError(err, p.window)
+				}
+				folderList.Refresh()
+				p.watchLibrary()
+			}
+		})
+	addBtn := widget.NewButtonWithIcon("add folder", theme.ContentAddIcon(), func() {
+		folderDialog := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				log.Println("dialog.NewFolderOpen", err)
+				return
+			}
+			if slices.Contains(p.library.folders, dir.Path()) {
+				return
+			}
+			p.library.folders = append(slices.Clone(p.library.folders), dir.Path())
+			folderList.Refresh()
+			if err := saveLibrary(p.library.folders, p.library.tracks); err != nil {
+				dialog.ShowError(err, p.window)
+			}
+			p.rescanLibrary()
+			p.watchLibrary()
+		}, p.window)
+		windowSize := p.window.Canvas().Size()
+		folderDialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+		folderDialog.Show()
+	})
+	foldersDialog := dialog.NewCustom("Library folders", "Close", container.NewBorder(nil, addBtn, nil, nil, folderList), p.window)
+	windowSize := p.window.Canvas().Size()
+	foldersDialog.Resize(fyne.NewSize(windowSize.Width*0.5, windowSize.Height*0.5))
+	foldersDialog.Show()
+}
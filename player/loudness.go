@@ -0,0 +1,254 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gopxl/beep"
+	"github.com/gorgemul/musicplayer/engine"
+	"math"
+	"time"
+)
+
+var ErrTooQuiet = errors.New("loudness: too quiet or too short to measure")
+
+const (
+	referenceLoudness = -18.0 // LUFS, the loudness ReplayGain 2.0 bring the tracks to
+	absoluteGate      = -70.0 // LUFS, blocks below are silence
+	relativeGate      = -10.0 // LU below the loudness of the blocks above the absolute gate
+	oversampling      = 4     // of the true peak
+	interpolatorTaps  = 12    // by phase of the oversampling filter
+)
+
+// interpolator is the oversampling lowpass filter of the true peak, a windowed sinc split into its phases
+var interpolator = func() (phases [oversampling][interpolatorTaps]float64) {
+	const length = oversampling * interpolatorTaps
+	for i := range length {
+		x := (float64(i) - float64(length-1)/2) / oversampling
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(length-1))
+		phases[i%oversampling][i/oversampling] = sinc * window
+	}
+	return phases
+}()
+
+// kFilter is a stage of the K-weighting of ITU-R BS.1770, in direct form I
+type kFilter struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *kFilter) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting design the high shelf and the high pass of the K-weighting for the rate
+func kWeighting(rate beep.SampleRate) [2]kFilter {
+	k := math.Tan(math.Pi * 1681.974450955533 / float64(rate))
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := kFilter{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	k = math.Tan(math.Pi * 38.13547087602444 / float64(rate))
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := kFilter{b0: 1, b1: -2, b2: 1, a1: 2 * (k*k - 1) / a0, a2: (1 - k/q + k*k) / a0}
+	return [2]kFilter{shelf, highPass}
+}
+
+// loudnessMeter measure the loudness of EBU R128 and the true peak of the samples added
+type loudnessMeter struct {
+	channels int
+	filters  [2][2]kFilter
+	hop      int       // samples in 100ms, blocks of 400ms overlap by 300ms so they start every hop
+	sum      float64   // of the squared weighted samples of the hop so far, channels summed
+	count    int       // samples of the hop so far
+	hops     []float64 // mean square of every hop done
+	history  [2][interpolatorTaps]float64
+	truePeak float64
+}
+
+func newLoudnessMeter(format beep.Format) *loudnessMeter {
+	m := &loudnessMeter{channels: min(max(format.NumChannels, 1), 2), hop: format.SampleRate.N(100 * time.Millisecond)}
+	m.filters[0] = kWeighting(format.SampleRate)
+	m.filters[1] = kWeighting(format.SampleRate)
+	return m
+}
+
+func (m *loudnessMeter) add(samples [][2]float64) {
+	for _, sample := range samples {
+		for c := range m.channels { // a mono file is decoded as two equal channels, but only count once
+			x := sample[c]
+			m.truePeak = max(m.truePeak, math.Abs(x))
+			history := &m.history[c]
+			copy(history[1:], history[:interpolatorTaps-1])
+			history[0] = x
+			for _, phase := range interpolator {
+				var y float64
+				for k, h := range phase {
+					y += h * history[k]
+				}
+				m.truePeak = max(m.truePeak, math.Abs(y))
+			}
+			y := m.filters[c][1].process(m.filters[c][0].process(x))
+			m.sum += y * y
+		}
+		m.count++
+		if m.count == m.hop {
+			m.hops = append(m.hops, m.sum/float64(m.hop))
+			m.sum, m.count = 0, 0
+		}
+	}
+}
+
+// blocks are the mean squares of the 400ms blocks
+func (m *loudnessMeter) blocks() []float64 {
+	var blocks []float64
+	for i := 3; i < len(m.hops); i++ {
+		blocks = append(blocks, (m.hops[i-3]+m.hops[i-2]+m.hops[i-1]+m.hops[i])/4)
+	}
+	return blocks
+}
+
+func blockLoudness(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+// integratedLoudness gate the blocks, negative infinity when every block is below the absolute gate
+func integratedLoudness(blocks []float64) float64 {
+	mean := func(threshold float64) (float64, bool) {
+		var sum float64
+		var count int
+		for _, block := range blocks {
+			if blockLoudness(block) > threshold {
+				sum += block
+				count++
+			}
+		}
+		return sum / float64(count), count > 0
+	}
+	ungated, ok := mean(absoluteGate)
+	if !ok {
+		return math.Inf(-1)
+	}
+	gated, ok := mean(max(blockLoudness(ungated)+relativeGate, absoluteGate))
+	if !ok {
+		return math.Inf(-1)
+	}
+	return blockLoudness(gated)
+}
+
+// loudness of a file as measured
+type loudness struct {
+	blocks   []float64
+	truePeak float64
+}
+
+// measureFile decode the whole file with the decoder of the engine
+func measureFile(ctx context.Context, path string) (loudness, error) {
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		return loudness{}, err
+	}
+	defer streamer.Close()
+	m := newLoudnessMeter(format)
+	samples := make([][2]float64, format.SampleRate.N(time.Second))
+	for {
+		if err := ctx.Err(); err != nil {
+			return loudness{}, err
+		}
+		n, ok := streamer.Stream(samples)
+		m.add(samples[:n])
+		if !ok {
+			break
+		}
+	}
+	if err := streamer.Err(); err != nil {
+		return loudness{}, err
+	}
+	return loudness{blocks: m.blocks(), truePeak: m.truePeak}, nil
+}
+
+// measuredTrack is the result of the loudness scan of a track
+type measuredTrack struct {
+	loudness   float64 // integrated, LUFS
+	replayGain ReplayGain
+}
+
+// measureTracks compute the ReplayGain 2.0 gains of the tracks, the album gain of the tracks grouped by artist and album.
+// An album only get an album gain when all its tracks in the library are measured, a part of it would skew the gain.
+// Failed tracks are left out and their errors returned, progress is called after every track
+func measureTracks(ctx context.Context, tracks []libraryTrack, library map[string]libraryTrack, measure func(ctx context.Context, path string) (loudness, error), progress func(done int)) (map[string]measuredTrack, []error) {
+	measured := map[string]measuredTrack{}
+	var errs []error
+	albums := map[string][]string{} // paths by artist and album
+	var order []string
+	measures := map[string]loudness{} // of the tracks of an album
+	done := 0
+	for _, track := range tracks {
+		if ctx.Err() != nil {
+			return nil, []error{ctx.Err()}
+		}
+		l, err := measure(ctx, track.Path)
+		done++
+		progress(done)
+		if err == nil && math.IsInf(integratedLoudness(l.blocks), -1) {
+			err = ErrTooQuiet
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, []error{ctx.Err()}
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", track.Path, err))
+			continue
+		}
+		integrated := integratedLoudness(l.blocks)
+		measured[track.Path] = measuredTrack{
+			loudness:   integrated,
+			replayGain: ReplayGain{TrackGain: referenceLoudness - integrated, TrackPeak: l.truePeak, HasTrackGain: true},
+		}
+		if track.Album != "" {
+			key := track.Artist + "\x00" + track.Album
+			if albums[key] == nil {
+				order = append(order, key)
+			}
+			albums[key] = append(albums[key], track.Path)
+			measures[track.Path] = l
+		}
+	}
+	albumSize := map[string]int{} // tracks in the library by artist and album
+	for _, track := range library {
+		albumSize[track.Artist+"\x00"+track.Album]++
+	}
+	for _, key := range order {
+		if len(albums[key]) < albumSize[key] {
+			continue
+		}
+		var all []float64
+		var peak float64
+		for _, path := range albums[key] {
+			all = append(all, measures[path].blocks...)
+			peak = max(peak, measures[path].truePeak)
+		}
+		integrated := integratedLoudness(all)
+		for _, path := range albums[key] {
+			m := measured[path]
+			m.replayGain.AlbumGain, m.replayGain.AlbumPeak, m.replayGain.HasAlbumGain = referenceLoudness-integrated, peak, true
+			measured[path] = m
+		}
+	}
+	return measured, errs
+}
@@ -0,0 +1,154 @@
+package player
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gopxl/beep"
+	"github.com/gorgemul/musicplayer/static"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoudness(t *testing.T) {
+	format := beep.Format{SampleRate: 48000, NumChannels: 2, Precision: 2}
+	sine := func(frequency, amplitude, phase float64, n int) [][2]float64 {
+		samples := make([][2]float64, n)
+		for i := range samples {
+			x := amplitude * math.Sin(2*math.Pi*frequency*float64(i)/float64(format.SampleRate)+phase)
+			samples[i] = [2]float64{x, x}
+		}
+		return samples
+	}
+
+	t.Run("integrated loudness", func(t *testing.T) {
+		m := newLoudnessMeter(format)
+		m.add(sine(1000, math.Pow(10, -23.0/20), 0, 10*48000))
+		assert.InDelta(t, -23, integratedLoudness(m.blocks()), 0.1, "a stereo 1kHz sine at -23 dBFS is -23 LUFS")
+	})
+	t.Run("gated silence", func(t *testing.T) {
+		m := newLoudnessMeter(format)
+		m.add(sine(1000, math.Pow(10, -23.0/20), 0, 5*48000))
+		m.add(make([][2]float64, 20*48000))
+		assert.InDelta(t, -23, integratedLoudness(m.blocks()), 0.2, "the silence is below the gates")
+
+		m = newLoudnessMeter(format)
+		m.add(make([][2]float64, 48000))
+		assert.True(t, math.IsInf(integratedLoudness(m.blocks()), -1))
+	})
+	t.Run("true peak", func(t *testing.T) {
+		m := newLoudnessMeter(format)
+		m.add(sine(12000, 1, math.Pi/4, 48000)) // samples fall at ±0.707, between the peaks
+		assert.InDelta(t, math.Sqrt(0.5), maxSample(sine(12000, 1, math.Pi/4, 48000)), 1e-9)
+		assert.InDelta(t, 1, m.truePeak, 0.05)
+	})
+
+	t.Run("track and album gains", func(t *testing.T) {
+		levels := map[string]float64{"a": -20, "b": -14, "c": -30, "d": -10, "quiet": math.Inf(-1)}
+		measure := func(ctx context.Context, path string) (loudness, error) {
+			if path == "broken" {
+				return loudness{}, errors.New("broken")
+			}
+			meanSquare := math.Pow(10, (levels[path]+0.691)/10)
+			return loudness{blocks: []float64{meanSquare, meanSquare}, truePeak: map[string]float64{"a": 0.5, "b": 0.9}[path]}, nil
+		}
+		tracks := []libraryTrack{
+			{Path: "a", Artist: "X", Album: "A"},
+			{Path: "broken", Artist: "X"},
+			{Path: "b", Artist: "X", Album: "A"},
+			{Path: "c", Artist: "X"},
+			{Path: "quiet", Artist: "X"},
+			{Path: "d", Artist: "X", Album: "D"},
+		}
+		library := map[string]libraryTrack{"d2": {Path: "d2", Artist: "X", Album: "D"}, "a3": {Path: "a3", Artist: "Y", Album: "A"}}
+		for _, track := range tracks {
+			library[track.Path] = track
+		}
+		var done []int
+		measured, errs := measureTracks(context.Background(), tracks, library, measure, func(n int) { done = append(done, n) })
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, done)
+		assert.Len(t, errs, 2)
+		assert.ErrorIs(t, errs[1], ErrTooQuiet)
+		assert.Len(t, measured, 4)
+		assert.InDelta(t, -20, measured["a"].loudness, 1e-9)
+		assert.InDelta(t, 2, measured["a"].replayGain.TrackGain, 1e-9)
+		assert.InDelta(t, -4, measured["b"].replayGain.TrackGain, 1e-9)
+		album := referenceLoudness + 0.691 - 10*math.Log10((math.Pow(10, (-20+0.691)/10)+math.Pow(10, (-14+0.691)/10))/2)
+		assert.InDelta(t, album, measured["a"].replayGain.AlbumGain, 1e-9)
+		assert.Equal(t, measured["a"].replayGain.AlbumGain, measured["b"].replayGain.AlbumGain)
+		assert.Equal(t, 0.9, measured["a"].replayGain.AlbumPeak)
+		assert.True(t, measured["a"].replayGain.HasAlbumGain)
+		assert.InDelta(t, 12, measured["c"].replayGain.TrackGain, 1e-9)
+		assert.False(t, measured["c"].replayGain.HasAlbumGain, "no album")
+		assert.True(t, measured["d"].replayGain.HasTrackGain)
+		assert.False(t, measured["d"].replayGain.HasAlbumGain, "a part of the album")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		measured, errs = measureTracks(ctx, tracks, library, measure, func(int) {})
+		assert.Nil(t, measured)
+		assert.ErrorIs(t, errs[0], context.Canceled)
+	})
+
+	t.Run("write tags", func(t *testing.T) {
+		dir := t.TempDir()
+		rg := ReplayGain{TrackGain: -7.5, TrackPeak: 0.95, AlbumGain: -6.25, AlbumPeak: 1.05, HasTrackGain: true, HasAlbumGain: true}
+		frames := "TIT2\x00\x00\x00\x07\x00\x00\x00Title\x00" + appendUserTextString("REPLAYGAIN_TRACK_GAIN", "+3.00 dB")
+		tag := "ID3\x03\x00\x00" + string(synchsafeBytes(uint32(len(frames)+4))) + frames + "\x00\x00\x00\x00"
+		tagged := filepath.Join(dir, "tagged.mp3")
+		assert.NoError(t, os.WriteFile(tagged, []byte(tag+"audio frames"), 0644))
+		assert.NoError(t, writeReplayGainTags(tagged, rg))
+		album, err := readTags(tagged)
+		assert.NoError(t, err)
+		assert.Equal(t, "Title", album.Title)
+		assert.InDelta(t, -7.5, album.ReplayGain.TrackGain, 1e-9, "old gain replaced")
+		assert.InDelta(t, 1.05, album.ReplayGain.AlbumPeak, 1e-6)
+		data, _ := os.ReadFile(tagged)
+		assert.Equal(t, "audio frames", string(data[len(data)-12:]))
+
+		assert.NoError(t, writeReplayGainTags(tagged, ReplayGain{TrackGain: 2, TrackPeak: 0.5, HasTrackGain: true}))
+		album, err = readTags(tagged)
+		assert.NoError(t, err)
+		assert.InDelta(t, 2, album.ReplayGain.TrackGain, 1e-9)
+		assert.InDelta(t, -6.25, album.ReplayGain.AlbumGain, 1e-9, "the album gain not measured is kept")
+
+		untagged := filepath.Join(dir, "untagged.mp3")
+		assert.NoError(t, os.WriteFile(untagged, []byte("audio frames"), 0644))
+		assert.NoError(t, writeReplayGainTags(untagged, ReplayGain{TrackGain: 1, TrackPeak: 0.5, HasTrackGain: true}))
+		album, err = readTags(untagged)
+		assert.NoError(t, err)
+		assert.Equal(t, ReplayGain{TrackGain: 1, TrackPeak: 0.5, HasTrackGain: true}, album.ReplayGain)
+
+		audio := bytes.Repeat([]byte("\xff\xfb\x90\x64\x00\x0f\xf0\x00"), 1000) // mpeg frame headers
+		demo := filepath.Join(dir, "demo.mp3")
+		assert.NoError(t, os.WriteFile(demo, append(slices.Clone(static.EmbedCoverMP3Bytes), audio...), 0644))
+		assert.NoError(t, writeReplayGainTags(demo, rg))
+		album, err = readAlbum(demo)
+		assert.NoError(t, err, "the audio is not read as frames")
+		assert.Equal(t, ExpectedTitle, album.Title)
+		assert.InDelta(t, -6.25, album.ReplayGain.AlbumGain, 1e-9)
+		assert.NotNil(t, album.Cover)
+		data, _ = os.ReadFile(demo)
+		assert.True(t, bytes.HasSuffix(data, audio))
+		size := len(data)
+		assert.NoError(t, writeReplayGainTags(demo, rg))
+		data, _ = os.ReadFile(demo)
+		assert.Equal(t, size, len(data), "the frames fit in the padding")
+	})
+}
+
+func maxSample(samples [][2]float64) float64 {
+	var peak float64
+	for _, s := range samples {
+		peak = max(peak, math.Abs(s[0]))
+	}
+	return peak
+}
+
+func appendUserTextString(description, value string) string {
+	return string(appendUserText(nil, 3, description, value))
+}
@@ -0,0 +1,109 @@
+package player
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"maps"
+	"os"
+	"regexp"
+)
+
+// measureLoudness ask whether to write the tags, then measure the tracks of the node in background
+func (p *Player) measureLoudness(node libraryNode) {
+	write := widget.NewCheck("Write ReplayGain tags to the MP3 files", nil)
+	write.SetChecked(p.settings.WriteReplayGainTags)
+	items := []*widget.FormItem{widget.NewFormItem("", write)}
+	dialog.ShowForm(fmt.Sprintf("Measure the loudness of %s (%d tracks)", node.label, len(node.tracks)), "Measure", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		if write.Checked != p.settings.WriteReplayGainTags {
+			p.settings.WriteReplayGainTags = write.Checked
+			if err := saveSettings(p.settings); err != nil {
+				log.Println("saveSettings", err)
+			}
+		}
+		p.startMeasure(node.tracks, write.Checked)
+	}, p.window)
+}
+
+// startMeasure decode the tracks in background, the loudness button cancel meanwhile
+func (p *Player) startMeasure(tracks []libraryTrack, writeTags bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.library.measure = cancel
+	p.UI.LibraryLoudnessBtn.SetText("cancel")
+	p.UI.LibraryLoudnessBtn.SetIcon(theme.CancelIcon())
+	p.UI.LibraryStatus.SetText(fmt.Sprintf("Measuring loudness... 0/%d tracks", len(tracks)))
+	library := p.library.tracks
+	go func() {
+		defer cancel()
+		measured, errs := measureTracks(ctx, tracks, library, measureFile, func(done int) {
+			fyne.Do(func() {
+				p.UI.LibraryStatus.SetText(fmt.Sprintf("Measuring loudness... %d/%d tracks", done, len(tracks)))
+			})
+		})
+		written := map[string]bool{}
+		for path, m := range measured {
+			if !writeTags || ctx.Err() != nil || !regexp.MustCompile(`\.(mp3)$`).MatchString(path) {
+				continue
+			}
+			if err := writeReplayGainTags(path, m.replayGain); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				continue
+			}
+			written[path] = true
+		}
+		fyne.Do(func() {
+			p.library.measure = nil
+			p.UI.LibraryLoudnessBtn.SetText("loudness")
+			p.UI.LibraryLoudnessBtn.SetIcon(theme.VolumeUpIcon())
+			if ctx.Err() != nil {
+				p.UI.LibraryStatus.SetText("Loudness measure cancelled")
+				return
+			}
+			for _, err := range errs {
+				log.Println("measureLoudness", err)
+			}
+			p.storeLoudness(measured, written)
+			p.UI.LibraryStatus.SetText(fmt.Sprintf("Loudness measured for %d tracks, %d failed", len(measured), len(errs)))
+		})
+	}()
+}
+
+// storeLoudness keep the measured loudness in the library. The files whose tags are written changed,
+// their size and time are updated so the next scan does not read them again
+func (p *Player) storeLoudness(measured map[string]measuredTrack, written map[string]bool) {
+	tracks := maps.Clone(p.library.tracks)
+	for path, m := range measured {
+		track, exist := tracks[path]
+		if !exist { // removed from the library meanwhile
+			continue
+		}
+		rg := m.replayGain
+		track.Loudness, track.ReplayGain = m.loudness, &rg
+		if info, err := os.Stat(path); err == nil && written[path] {
+			track.ModTime, track.Size = info.ModTime(), info.Size()
+		}
+		tracks[path] = track
+	}
+	p.setLibraryTracks(tracks)
+	if err := saveLibrary(p.library.folders, tracks); err != nil {
+		dialog.ShowError(err, p.window)
+	}
+}
+
+// carryLoudness keep the loudness measured while the library was scanned, of the files unchanged since
+func carryLoudness(current, tracks map[string]libraryTrack) {
+	for path, track := range tracks {
+		measured, exist := current[path]
+		if exist && track.ReplayGain == nil && measured.ReplayGain != nil && measured.ModTime.Equal(track.ModTime) && measured.Size == track.Size {
+			track.Loudness, track.ReplayGain = measured.Loudness, measured.ReplayGain
+			tracks[path] = track
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package player
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// metadata is what the playlist columns show for a song, empty or 0 when unknown
+type metadata struct {
+	title    string
+	artist   string
+	album    string
+	genre    string
+	year     int
+	track    int
+	disc     int
+	duration time.Duration
+	// statistics from the play history, filled by the player rather than read from the file
+	plays      int
+	skips      int
+	lastPlayed time.Time
+}
+
+// readMetadata read the tags without cover and the duration of the audio
+func readMetadata(audioPath string) (metadata, error) {
+	var m metadata
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return m, err
+	}
+	var album Album
+	switch {
+	case regexp.MustCompile(`\.(flac)$`).MatchString(audioPath):
+		album, err = parseFLAC(f, false)
+	case regexp.MustCompile(`\.(mp3)$`).MatchString(audioPath):
+		var fileStream []byte
+		if fileStream, err = readTag(f); err == nil {
+			album, err = parseTag(fileStream, false)
+		}
+	}
+	f.Close()
+	if err == nil {
+		if album.Title != "Unknown Title" {
+			m.title = album.Title
+		}
+		if album.Artist != "Unknown Artist" {
+			m.artist = album.Artist
+		}
+		m.album = album.AlbumTitle
+		m.genre = album.Genre
+		m.year = album.Year
+		m.track = album.Track
+		m.disc = album.Disc
+	}
+	m.duration, err = audioDuration(audioPath)
+	return m, err
+}
+
+// songInfo merge what the playlist know about the song with the file metadata, title fallback to the filename
+func songInfo(s song, m metadata) metadata {
+	info := metadata{title: s.title, artist: s.artist, album: s.album, track: s.track, duration: s.duration}
+	if info.title == "" {
+		info.title = m.title
+	}
+	if info.title == "" {
+		info.title = strings.TrimSuffix(filepath.Base(s.path), filepath.Ext(s.path))
+	}
+	if info.artist == "" {
+		info.artist = m.artist
+	}
+	if info.album == "" {
+		info.album = m.album
+	}
+	info.genre = m.genre
+	info.year = m.year
+	if s.virtual() {
+		if info.duration == 0 && m.duration > s.start {
+			info.duration = m.duration - s.start
+		}
+		return info
+	}
+	if info.track == 0 {
+		info.track = m.track
+	}
+	info.disc = m.disc
+	if info.duration == 0 {
+		info.duration = m.duration
+	}
+	return info
+}
+
+type column int
+
+const (
+	trackColumn column = iota
+	titleColumn
+	artistColumn
+	albumColumn
+	durationColumn
+	playsColumn
+	lastPlayedColumn
+	skipsColumn
+)
+
+var columnNames = []string{"#", "Title", "Artist", "Album", "Duration", "Plays", "Last played", "Skips"}
+
+// sortSongs sort stably by the column, track number sort within the album
+func sortSongs(songs []song, info func(song) metadata, by column, descending bool) {
+	infos := make([]metadata, len(songs))
+	indexes := make([]int, len(songs))
+	for i, s := range songs {
+		indexes[i] = i
+		infos[i] = info(s)
+	}
+	slices.SortStableFunc(indexes, func(i, j int) int {
+		a, b := infos[i], infos[j]
+		var c int
+		switch by {
+		case trackColumn:
+			c = cmp.Or(naturalCompare(a.album, b.album), cmp.Compare(a.disc, b.disc), cmp.Compare(a.track, b.track))
+		case titleColumn:
+			c = naturalCompare(a.title, b.title)
+		case artistColumn:
+			c = naturalCompare(a.artist, b.artist)
+		case albumColumn:
+			c = naturalCompare(a.album, b.album)
+		case durationColumn:
+			c = cmp.Compare(a.duration, b.duration)
+		case playsColumn:
+			c = cmp.Compare(a.plays, b.plays)
+		case lastPlayedColumn:
+			c = a.lastPlayed.Compare(b.lastPlayed)
+		case skipsColumn:
+			c = cmp.Compare(a.skips, b.skips)
+		}
+		if descending {
+			return -c
+		}
+		return c
+	})
+	sorted := make([]song, len(songs))
+	for i, index := range indexes {
+		sorted[i] = songs[index]
+	}
+	copy(songs, sorted)
+}
@@ -0,0 +1,59 @@
+package player
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Run("read tags", func(t *testing.T) {
+		frame := func(id, content string) string {
+			size := make([]byte, 4)
+			binary.BigEndian.PutUint32(size, uint32(len(content)+2))
+			return id + string(size) + "\x00\x00" + "\x00" + content + "\x00"
+		}
+		frames := frame("TIT2", "Song") + frame("TALB", "Record") + frame("TRCK", "4")
+		path := filepath.Join(t.TempDir(), "a.mp3")
+		assert.NoError(t, os.WriteFile(path, []byte("ID3\x03\x00\x00\x00\x00\x00"+string(rune(len(frames)))+frames), 0644))
+		m, err := readMetadata(path)
+		assert.Error(t, err) // no audio frame to decode the duration from
+		assert.Equal(t, metadata{title: "Song", album: "Record", track: 4}, m)
+	})
+	t.Run("song info", func(t *testing.T) {
+		m := metadata{title: "Tag Title", artist: "Tag Artist", album: "Tag Album", track: 3, disc: 1, duration: time.Minute}
+		assert.Equal(t, metadata{title: "Tag Title", artist: "Tag Artist", album: "Tag Album", track: 3, disc: 1, duration: time.Minute}, songInfo(song{path: "/a/01 x.mp3"}, m))
+		assert.Equal(t, metadata{title: "01 x"}, songInfo(song{path: "/a/01 x.mp3"}, metadata{}))
+		virtual := song{path: "/a/album.flac", title: "Second", track: 2, start: 20 * time.Second}
+		assert.Equal(t, metadata{title: "Second", artist: "Tag Artist", album: "Tag Album", track: 2, duration: 40 * time.Second}, songInfo(virtual, m))
+	})
+	t.Run("sort", func(t *testing.T) {
+		infos := map[string]metadata{
+			"a": {title: "b", album: "X", track: 2, duration: 3},
+			"b": {title: "a", album: "X", track: 1, duration: 1},
+			"c": {title: "C", album: "W", track: 9, duration: 2},
+		}
+		info := func(s song) metadata { return infos[s.path] }
+		order := func(songs []song) string {
+			var s string
+			for _, song := range songs {
+				s += song.path
+			}
+			return s
+		}
+		songs := []song{{path: "a"}, {path: "b"}, {path: "c"}}
+		sortSongs(songs, info, trackColumn, false)
+		assert.Equal(t, "cba", order(songs))
+		sortSongs(songs, info, titleColumn, false)
+		assert.Equal(t, "bac", order(songs))
+		sortSongs(songs, info, durationColumn, true)
+		assert.Equal(t, "acb", order(songs))
+	})
+	t.Run("footer", func(t *testing.T) {
+		assert.Equal(t, "1 track, 03:00", footerText(1, 3*time.Minute, 0))
+		assert.Equal(t, "3 tracks, 01:00:05 (1 without duration)", footerText(3, time.Hour+5*time.Second, 1))
+	})
+}
@@ -2,17 +2,15 @@ package player
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/gopxl/beep"
-	"github.com/gopxl/beep/mp3"
-	"github.com/gopxl/beep/speaker"
-	"github.com/gopxl/beep/wav"
+	"github.com/gorgemul/musicplayer/engine"
 	"github.com/gorgemul/musicplayer/static"
 	"image"
 	"image/color"
@@ -20,68 +18,107 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
-	"sync"
 	"time"
 )
 
 type Player struct {
-	album    Album
-	streamer beep.StreamSeekCloser
-	ctrl     *beep.Ctrl
-	format   beep.Format
-	progress binding.Float
-	renderer struct {
-		lock   sync.Mutex
-		render bool
-		ticker *time.Ticker
-		stop   chan bool
+	album     Album
+	engine    *engine.Engine
+	state     engine.State
+	window    fyne.Window
+	playlists []*Playlist
+	saved     []string
+	scan      scanOptions // last used directory import options
+	settings  settings
+	history   []historyEntry // oldest first
+	stats     map[historyKey]trackStats
+	current   struct {
+		song    song // the loaded song, kept apart from the playlist which may be edited while playing
+		started time.Time
 	}
-	UI struct {
-		AlbumCover    *canvas.Image
-		AlbumTitle    *canvas.Text
-		AlbumArtist   *canvas.Text
-		PrevBtn       *widget.Button
-		PlayBtn       *widget.Button
-		NextBtn       *widget.Button
-		Slider        *widget.Slider
-		ProgressLabel *widget.Label
-		DurationLabel *widget.Label
+	metadata *lazyCache[metadata]
+	covers   *lazyCache[image.Image] // album cover thumbnails of the library
+	peaks    *lazyCache[[]uint8]     // waveforms of the played files
+	library  struct {
+		folders   []string
+		tracks    map[string]libraryTrack
+		nodes     map[string]libraryNode
+		children  map[string][]string
+		albums    []gridAlbum
+		cancel    context.CancelFunc // cancel the running scan, nil when not scanning
+		rescan    bool               // whether to scan again when the running scan finish
+		stopWatch context.CancelFunc // stop watching the folders, nil when not watching
+		measure   context.CancelFunc // cancel the running loudness measure, nil when not measuring
+	}
+	unsubscribe    func()
+	updatingSlider bool   // whether the slider is moved by the playback rather than the user
+	seek           uint64 // id of the last seek requested by the user, older positions are stale
+	UI             struct {
+		AlbumCover          *canvas.Image
+		AlbumTitle          *canvas.Text
+		AlbumArtist         *canvas.Text
+		PrevBtn             *widget.Button
+		PlayBtn             *widget.Button
+		NextBtn             *widget.Button
+		VisualizerBtn       *widget.Button
+		EqualizerBtn        *widget.Button
+		ReplayGainBtn       *widget.Button
+		SpeedBtn            *widget.Button
+		Visualizer          *visualizer
+		Slider              *seekSlider
+		Waveform            *waveform
+		ProgressLabel       *widget.Label
+		DurationLabel       *tappableLabel
+		Playlists           *container.DocTabs
+		SavedPlaylists      *widget.List
+		SmartPlaylistBtn    *widget.Button
+		Library             *widget.Tree
+		LibraryStatus       *widget.Label
+		LibraryAddBtn       *widget.Button
+		LibraryFoldersBtn   *widget.Button
+		LibraryRescanBtn    *widget.Button
+		LibraryLoudnessBtn  *widget.Button
+		AlbumGrid           *widget.GridWrap
+		AlbumGridMode       *widget.Select
+		History             *widget.List
+		HistoryThresholdBtn *widget.Button
+		HistoryExportBtn    *widget.Button
+		HistoryReportBtn    *widget.Button
 	}
 	*Playlist
 }
 
 func New(window fyne.Window) *Player {
 	var p Player
-	var pl Playlist
-	p.renderer.stop = make(chan bool)
-	p.progress = binding.NewFloat()
-	p.progress.AddListener(binding.NewDataListener(func() {
-		if second, err := p.progress.Get(); err == nil {
-			go func(s float64) {
-				p.renderer.lock.Lock()
-				playerRenderEvent := p.renderer.render
-				p.renderer.render = false
-				p.renderer.lock.Unlock()
-				if !playerRenderEvent {
-					// when slider is draged, if in the middle of the progress render, would cause the progress flashback to previous state
-					if p.renderer.ticker != nil {
-						p.renderer.ticker.Reset(1 * time.Second)
-					}
-					if p.hasStream() {
-						speaker.Lock()
-						p.streamer.Seek(int(second * float64(p.format.SampleRate)))
-						speaker.Unlock()
-					}
-					fyne.Do(func() {
-						p.UI.ProgressLabel.Text = formatTime(second)
-						p.UI.ProgressLabel.Refresh()
-					})
-				}
-			}(second)
+	p.window = window
+	p.metadata = newLazyCache(func(path string) metadata {
+		m, _ := readMetadata(path) // unreadable file is cached as unknown, so it is not read again
+		return m
+	}, func() {
+		fyne.Do(func() {
+			for _, pl := range p.playlists {
+				p.refreshPlaylist(pl)
+			}
+		})
+	})
+	p.peaks = newLazyCache(func(path string) []uint8 {
+		peaks, err := readCachedPeaks(path)
+		if err != nil {
+			log.Println("readCachedPeaks", err)
 		}
-	}))
+		return peaks
+	}, func() {
+		fyne.Do(p.showWaveform)
+	})
+	p.engine = engine.New()
+	p.unsubscribe = p.engine.Subscribe(func(event engine.Event) {
+		fyne.Do(func() {
+			p.handle(event)
+		})
+	})
 	if image, _, err := image.Decode(bytes.NewReader(static.DefaultCoverBytes)); err == nil {
 		p.UI.AlbumCover = canvas.NewImageFromImage(image)
 	} else {
@@ -97,280 +134,313 @@ func New(window fyne.Window) *Player {
 	p.UI.AlbumArtist.Alignment = fyne.TextAlignCenter
 	p.UI.AlbumArtist.TextSize = 16
 	p.UI.PrevBtn = widget.NewButtonWithIcon("", theme.MediaSkipPreviousIcon(), func() {
-		pl.UI.entries[pl.playingIndex].Label.Importance = widget.MediumImportance
-		pl.UI.entries[pl.playingIndex].Label.Refresh()
-		pl.playingIndex--
-		p.play(pl.songs[pl.playingIndex].path)
+		if err := p.engine.Prev(); err != nil {
+			log.Println("engine.Prev", err)
+		}
 	})
 	p.UI.NextBtn = widget.NewButtonWithIcon("", theme.MediaSkipNextIcon(), func() {
-		pl.UI.entries[pl.playingIndex].Label.Importance = widget.MediumImportance
-		pl.UI.entries[pl.playingIndex].Label.Refresh()
-		pl.playingIndex++
-		p.play(pl.songs[pl.playingIndex].path)
+		if err := p.engine.Next(); err != nil {
+			log.Println("engine.Next", err)
+		}
 	})
-	p.UI.PlayBtn = widget.NewButtonWithIcon("", theme.MediaPauseIcon(), func() {
-		if p.ctrl.Paused {
-			p.resume()
-		} else {
-			p.pause()
+	p.UI.PlayBtn = widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		if p.state == engine.Playing || p.state == engine.Loading {
+			p.engine.Pause()
+		} else if err := p.engine.Play(); err != nil {
+			log.Println("engine.Play", err)
+		}
+	})
+	p.UI.Visualizer = newVisualizer(p.engine.Tap())
+	p.UI.VisualizerBtn = widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+		p.settings.ShowVisualizer = !p.settings.ShowVisualizer
+		if err := saveSettings(p.settings); err != nil {
+			log.Println("saveSettings", err)
+		}
+		p.showVisualizer()
+	})
+	p.UI.EqualizerBtn = widget.NewButton("EQ", p.showEqualizer)
+	p.UI.ReplayGainBtn = widget.NewButton("ReplayGain", p.showReplayGain)
+	p.UI.SpeedBtn = widget.NewButton(speedText(1), p.showSpeed)
+	p.UI.Slider = newSeekSlider()
+	p.UI.Slider.OnChanged = func(second float64) {
+		if p.updatingSlider {
+			return
+		}
+		p.showProgress(second) // preview while dragging, the seek wait for the release
+	}
+	p.UI.Slider.OnChangeEnded = func(second float64) {
+		if p.updatingSlider {
+			return
+		}
+		p.seekTo(second)
+	}
+	p.UI.Waveform = newWaveform(func(ratio float64) {
+		if p.state != engine.Stopped {
+			p.seekTo(ratio * p.UI.Slider.Max)
 		}
 	})
-	p.UI.Slider = widget.NewSliderWithData(0, 0, p.progress)
 	p.UI.ProgressLabel = widget.NewLabel("00:00")
-	p.UI.DurationLabel = widget.NewLabel("00:00")
+	p.UI.DurationLabel = newTappableLabel("00:00", func() {
+		p.settings.ShowRemaining = !p.settings.ShowRemaining
+		if err := saveSettings(p.settings); err != nil {
+			log.Println("saveSettings", err)
+		}
+		p.showProgress(p.UI.Slider.Value)
+	})
 	p.UI.PrevBtn.Disable()
 	p.UI.PlayBtn.Disable()
 	p.UI.NextBtn.Disable()
 	p.UI.Slider.Disable()
-	pl.playingIndex = -1
-	pl.UI.ImportFromFileBtn = widget.NewButtonWithIcon("file", theme.ContentAddIcon(), func() {
-		dialog := dialog.NewFileOpen(func(file fyne.URIReadCloser, err error) {
-			if err != nil || file == nil {
-				log.Println("dialog.NewFolderOpen", err)
-				return
-			}
-			defer file.Close()
-			newSong := song{file.URI().Path(), file.URI().Name()}
-			if err := isValidAudio(newSong); err != nil {
-				dialog.ShowError(err, window)
-				return
-			}
-			if index := slices.IndexFunc(pl.songs, func(s song) bool {
-				return s.path == newSong.path
-			}); index != -1 {
-				dialog.ShowError(fmt.Errorf("%q already exist!", newSong.name), window)
-				return
-			}
-			pl.songs = append(pl.songs, newSong)
-			pl.UI.List.Refresh()
-		}, window)
-		windowSize := window.Canvas().Size()
-		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
-		dialog.Show()
-	})
-	pl.UI.ImportFromDirBtn = widget.NewButtonWithIcon("directory", theme.ContentAddIcon(), func() {
-		dialog := dialog.NewFolderOpen(func(files fyne.ListableURI, err error) {
-			if err != nil || files == nil {
-				log.Println("dialog.NewFolderOpen", err)
-				return
-			}
-			fileList, err := files.List()
-			if err != nil {
-				log.Println("dialog.NewFolderOpen", err)
-				return
-			}
-			before := len(pl.songs)
-			for _, file := range fileList {
-				newSong := song{file.Path(), file.Name()}
-				exist := slices.IndexFunc(pl.songs, func(s song) bool {
-					return s.path == newSong.path
-				}) != -1
-				if !exist && isValidAudio(newSong) == nil {
-					pl.songs = append(pl.songs, newSong)
-				}
-			}
-			if len(pl.songs) == before {
-				dialog.ShowError(fmt.Errorf("No new mp3 file in selected directory"), window)
-			} else {
-				pl.UI.List.Refresh()
-			}
-		}, window)
-		windowSize := window.Canvas().Size()
-		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
-		dialog.Show()
-	})
-	pl.UI.List = widget.NewList(
+	p.Playlist = p.newPlaylist("", nil)
+	p.UI.Playlists = container.NewDocTabs(p.Playlist.tab)
+	p.UI.Playlists.CreateTab = func() *container.TabItem {
+		return p.newPlaylist("", nil).tab
+	}
+	// closing the playing playlist keep the playing song, like clearing it, but next and previous have nothing to follow
+	p.UI.Playlists.OnClosed = func(tab *container.TabItem) {
+		p.playlists = slices.DeleteFunc(p.playlists, func(pl *Playlist) bool {
+			return pl.tab == tab
+		})
+		if p.Playlist.tab != tab {
+			return
+		}
+		if len(p.playlists) == 0 {
+			p.UI.Playlists.Append(p.newPlaylist("", nil).tab)
+		}
+		p.Playlist = p.playlists[0]
+		if index := slices.IndexFunc(p.playlists, func(pl *Playlist) bool { return pl.tab == p.UI.Playlists.Selected() }); index != -1 {
+			p.Playlist = p.playlists[index]
+		}
+		p.engine.SetQueue(nil, -1)
+		p.UI.PrevBtn.Disable()
+		p.UI.NextBtn.Disable()
+	}
+	p.UI.SavedPlaylists = widget.NewList(
 		func() int {
-			return len(pl.songs)
+			return len(p.saved)
 		},
 		func() fyne.CanvasObject {
-			var e entry
-			label := widget.NewLabel("playlistEntry")
-			e.Label = label
-			e.onDoubleTapped = func(audioPath string) {
-				if pl.playingIndex == e.index {
-					return
-				}
-				if pl.playingIndex != -1 {
-					pl.UI.entries[pl.playingIndex].Importance = widget.MediumImportance
-					pl.UI.entries[pl.playingIndex].Refresh()
-				}
-				pl.playingIndex = e.index
-				p.play(audioPath)
-			}
-			return &e
+			return widget.NewLabel("savedPlaylist")
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
-			e := o.(*entry)
-			e.SetText(pl.songs[i].name)
-			e.song = pl.songs[i]
-			e.index = i
-			pl.UI.entries = append(pl.UI.entries, *e)
+			o.(*widget.Label).SetText(p.saved[i])
 		})
-	p.Playlist = &pl
+	p.UI.SavedPlaylists.OnSelected = func(i widget.ListItemID) {
+		p.openPlaylist(p.saved[i])
+		p.UI.SavedPlaylists.UnselectAll()
+	}
+	p.refreshSavedPlaylists()
+	p.UI.SmartPlaylistBtn = widget.NewButtonWithIcon("smart playlist", theme.ContentAddIcon(), p.createSmartPlaylist)
+	p.newHistoryView()
+	p.showVisualizer()
+	p.engine.SetEqualizer(p.settings.Equalizer)
+	p.newLibraryBrowser()
+	window.SetOnDropped(p.drop)
 	return &p
 }
 
-func (p *Player) loadAudio(audioPath string) {
-	var (
-		album    Album
-		err      error
-		streamer beep.StreamSeekCloser
-		format   beep.Format
-	)
-	mp3Format := regexp.MustCompile(`\.(mp3)$`)
-	f, err := os.Open(audioPath)
-	if mp3Format.MatchString(audioPath) {
-		assertNoError(err)
-		data, err := io.ReadAll(f)
-		assertNoError(err)
-		album, err = parse(data) // only support mp3 format to album info
-		assertNoError(err)
-		_, err = f.Seek(0, io.SeekStart)
-		assertNoError(err)
-		streamer, format, err = mp3.Decode(f)
-	} else {
-		album.Cover, _, err = image.Decode(bytes.NewReader(static.DefaultCoverBytes))
-		assertNoError(err)
-		album.Artist = "Unknown Artist"
-		album.Title = "Unknown Title"
-		streamer, format, err = wav.Decode(f)
+// Close log how far the playing song got and stop the engine, call it once the window is closed
+func (p *Player) Close() {
+	p.unsubscribe() // the UI no longer handle the events
+	if p.engine.State() != engine.Stopped {
+		if err := appendHistory(p.historyEntry(p.engine.Position(), p.engine.Duration(), false)); err != nil {
+			log.Println("appendHistory", err)
+		}
+	}
+	if err := p.engine.Close(); err != nil {
+		log.Println("engine.Close", err)
 	}
-	assertNoError(err)
-	p.album = album
-	p.streamer = streamer
-	p.format = format
 }
 
-func (p *Player) play(audioPath string) {
-	if !p.hasStream() {
-		p.loadAudio(audioPath)
-		err := speaker.Init(p.format.SampleRate, p.format.SampleRate.N(time.Second/10))
-		assertNoError(err)
-		p.ctrl = &beep.Ctrl{Streamer: p.streamer, Paused: false}
-		fyne.Do(func() {
-			p.UI.PlayBtn.Enable()
-			p.UI.Slider.Enable()
-		})
-	} else {
-		if !p.ctrl.Paused {
-			p.pause()
+// loadAlbum read the cover, title and artist shown for the song, the default cover when the file has none
+func loadAlbum(s song) Album {
+	album := Album{Title: "Unknown Title", Artist: "Unknown Artist"}
+	if f, err := os.Open(s.path); err == nil {
+		if regexp.MustCompile(`\.(mp3)$`).MatchString(s.path) {
+			if data, err := io.ReadAll(f); err == nil {
+				if parsed, err := parse(data); err == nil { // only support mp3 format to album info
+					album = parsed
+				}
+			}
+		} else if parsed, err := parseFLAC(f, true); err == nil { // only flac carry tag besides mp3, parsing wav simply fail
+			album = parsed
 		}
-		p.streamer.Close()
-		speaker.Clear()
-		p.loadAudio(audioPath)
-		p.ctrl.Streamer = p.streamer
+		f.Close()
 	}
-	max := p.format.SampleRate.D(p.streamer.Len()).Round(time.Second).Seconds()
-	fyne.Do(func() {
-		p.UI.AlbumCover.Image = p.album.Cover
-		p.UI.AlbumTitle.Text = p.album.Title
-		p.UI.AlbumArtist.Text = p.album.Artist
-		p.UI.Slider.Max = max
-		p.UI.AlbumCover.Refresh()
-		p.UI.AlbumTitle.Refresh()
-		p.UI.AlbumArtist.Refresh()
-		p.progress.Set(0)
-		p.UI.DurationLabel.Text = formatTime(max)
-		p.UI.DurationLabel.Refresh()
-		p.Playlist.UI.entries[p.Playlist.playingIndex].Importance = widget.HighImportance
-		p.Playlist.UI.entries[p.Playlist.playingIndex].Refresh()
-		if p.Playlist.playingIndex == 0 {
-			p.UI.PrevBtn.Disable()
-		} else {
-			p.UI.PrevBtn.Enable()
-		}
-		if p.Playlist.playingIndex == len(p.Playlist.songs)-1 {
-			p.UI.NextBtn.Disable()
-		} else {
-			p.UI.NextBtn.Enable()
-		}
-		p.UI.PrevBtn.Refresh()
-		p.UI.NextBtn.Refresh()
-	})
-	speaker.Play(beep.Seq(p.ctrl, beep.Callback(p.replay)))
-	p.resume()
+	if album.Cover == nil {
+		album.Cover, _, _ = image.Decode(bytes.NewReader(static.DefaultCoverBytes))
+	}
+	// virtual track of a cue sheet is described by the sheet rather than the tag of the whole file
+	if s.title != "" && (album.Title == "Unknown Title" || s.virtual()) {
+		album.Title = s.title
+	}
+	if s.artist != "" && (album.Artist == "Unknown Artist" || s.virtual()) {
+		album.Artist = s.artist
+	}
+	return album
 }
 
-func (p *Player) pause() {
-	p.renderer.stop <- true
-	speaker.Lock()
-	p.ctrl.Paused = true
-	// when paused, if not update progress, would cuase next render move the slider 2 seconds
-	p.progress.Set(p.format.SampleRate.D(p.streamer.Position()).Round(time.Second).Seconds())
-	speaker.Unlock()
-	fyne.Do(func() {
-		p.UI.PlayBtn.SetIcon(theme.MediaPlayIcon())
-		p.UI.PlayBtn.Refresh()
-	})
+func audioDuration(audioPath string) (time.Duration, error) {
+	streamer, format, err := engine.Decode(audioPath)
+	if err != nil {
+		return 0, err
+	}
+	defer streamer.Close()
+	return format.SampleRate.D(streamer.Len()), nil
 }
 
-func (p *Player) resume() {
-	speaker.Lock()
-	p.ctrl.Paused = false
-	speaker.Unlock()
-	fyne.Do(func() {
-		p.UI.PlayBtn.SetIcon(theme.MediaPauseIcon())
-		p.UI.PlayBtn.Refresh()
-	})
-	go func() {
-		p.renderer.ticker = time.NewTicker(1 * time.Second)
-		defer func() {
-			p.renderer.ticker.Stop()
-			p.renderer.ticker = nil
-		}()
-		for {
-			select {
-			case <-p.renderer.stop:
-				return
-			case <-p.renderer.ticker.C:
-				speaker.Lock()
-				p.renderer.lock.Lock()
-				currentProgress := p.format.SampleRate.D(p.streamer.Position()).Round(time.Second).Seconds()
-				fyne.Do(func() {
-					p.progress.Set(currentProgress)
-					p.UI.ProgressLabel.Text = formatTime(currentProgress)
-					p.UI.ProgressLabel.Refresh()
-				})
-				p.renderer.render = true
-				speaker.Unlock()
-				p.renderer.lock.Unlock()
-			}
-		}
-	}()
+func (s song) engineTrack() engine.Track {
+	return engine.Track{Path: s.path, Start: s.start, End: s.end}
 }
 
-func (p *Player) replay() {
-	if len(p.Playlist.songs) == 1 {
-		// if not use go routine will cause deadlock since modify speaker status inside speaker play method
+func engineTracks(songs []song) []engine.Track {
+	tracks := make([]engine.Track, len(songs))
+	for i, s := range songs {
+		tracks[i] = s.engineTrack()
+	}
+	return tracks
+}
+
+// play load the playing playlist into the engine and start the song at index
+func (p *Player) play(index int) {
+	p.Playlist.playingIndex = index
+	if err := p.engine.Load(engineTracks(p.Playlist.songs), index); err != nil {
+		return // shown by the Failed event
+	}
+	p.engine.Play()
+}
+
+// syncQueue let the engine know the edited playing playlist, so next and previous follow it
+func (p *Player) syncQueue() {
+	p.engine.SetQueue(engineTracks(p.Playlist.songs), p.Playlist.playingIndex)
+}
+
+// handle update the UI by the engine event, called on the UI goroutine
+func (p *Player) handle(event engine.Event) {
+	switch event.Kind {
+	case engine.StateChanged:
+		if p.state == engine.Ended && event.State == engine.Playing {
+			p.current.started = time.Now()
+		}
+		p.state = event.State
+		if event.State == engine.Playing || event.State == engine.Loading {
+			p.UI.PlayBtn.SetIcon(theme.MediaPauseIcon())
+		} else {
+			p.UI.PlayBtn.SetIcon(theme.MediaPlayIcon())
+		}
+		if event.State == engine.Stopped {
+			p.UI.PlayBtn.Disable()
+			p.UI.Slider.Disable()
+		} else {
+			p.UI.PlayBtn.Enable()
+			p.UI.Slider.Enable()
+		}
+	case engine.TrackChanged:
+		s := song{path: event.Track.Path, name: filepath.Base(event.Track.Path), start: event.Track.Start, end: event.Track.End}
+		index := slices.IndexFunc(p.Playlist.songs, func(other song) bool {
+			return other.engineTrack() == event.Track
+		})
+		if index != -1 {
+			s = p.Playlist.songs[index]
+		}
+		p.Playlist.playingIndex = index
+		p.current.song, p.current.started = s, time.Now()
+		p.applySpeed()
+		p.UI.Slider.Max = event.Duration.Seconds()
+		p.setProgress(0)
+		p.showWaveform()
+		p.refreshPlaylist(p.Playlist)
+		p.updateSkipButtons()
 		go func() {
-			p.streamer.Seek(0)
-			p.pause()
+			album := loadAlbum(s)
 			fyne.Do(func() {
-				p.UI.PlayBtn.SetIcon(theme.MediaPlayIcon())
-				p.UI.PlayBtn.Refresh()
+				if !p.current.song.is(s) {
+					return
+				}
+				p.album = album
+				p.UI.AlbumCover.Image = album.Cover
+				p.UI.AlbumTitle.Text = album.Title
+				p.UI.AlbumArtist.Text = album.Artist
+				p.UI.AlbumCover.Refresh()
+				p.UI.AlbumTitle.Refresh()
+				p.UI.AlbumArtist.Refresh()
 			})
-			speaker.Play(beep.Seq(p.ctrl, beep.Callback(p.replay)))
-		}()
-	} else {
-		go func() {
-			p.Playlist.UI.entries[p.Playlist.playingIndex].Importance = widget.MediumImportance
-			p.Playlist.UI.entries[p.Playlist.playingIndex].Refresh()
-			p.Playlist.playingIndex++
-			p.Playlist.playingIndex %= len(p.Playlist.songs)
-			p.play(p.Playlist.songs[p.Playlist.playingIndex].path)
 		}()
+	case engine.TrackLeft:
+		p.addHistory(p.historyEntry(event.Position, event.Duration, event.Position < event.Duration))
+	case engine.Ticked:
+		// a tick from before the seek would move the slider back, and the dragged slider show where to seek
+		if event.Seek >= p.seek && !p.UI.Slider.dragging {
+			p.setProgress(event.Position.Seconds())
+		}
+	case engine.Seeked:
+		if event.Seek == p.seek && event.Err == nil {
+			p.setProgress(event.Position.Seconds())
+		}
+	case engine.Failed:
+		dialog.ShowError(event.Err, p.window)
 	}
 }
 
-func (p *Player) hasStream() bool {
-	return p.streamer != nil
+// setProgress move the slider without seeking
+func (p *Player) setProgress(seconds float64) {
+	p.updatingSlider = true
+	p.UI.Slider.SetValue(seconds)
+	p.updatingSlider = false
+	p.showProgress(seconds)
 }
 
-func assertNoError(err error) {
-	if err != nil {
-		log.Fatal(err)
+// seekTo ask the engine to seek, the progress show the target meanwhile
+func (p *Player) seekTo(seconds float64) {
+	p.seek = p.engine.RequestSeek(time.Duration(seconds * float64(time.Second)))
+	p.setProgress(seconds)
+}
+
+// showWaveform draw the peaks of the current song, nothing until they are read in background
+func (p *Player) showWaveform() {
+	if p.current.song.path == "" {
+		return
+	}
+	peaks, _ := p.peaks.get(p.current.song.path)
+	p.UI.Waveform.setPeaks(trackPeaks(peaks, p.current.song.start, p.current.song.end))
+}
+
+// showProgress write the position, and the time left when it is shown rather than the duration
+func (p *Player) showProgress(seconds float64) {
+	p.UI.ProgressLabel.SetText(formatTime(seconds))
+	p.UI.DurationLabel.SetText(durationText(seconds, p.UI.Slider.Max, p.settings.ShowRemaining))
+	if p.UI.Slider.Max > 0 {
+		p.UI.Waveform.setProgress(seconds / p.UI.Slider.Max)
+	}
+}
+
+// showVisualizer show the visualizer when it is turned on, it is only analyzing while shown
+func (p *Player) showVisualizer() {
+	if p.settings.ShowVisualizer {
+		p.UI.Visualizer.Show()
+		p.UI.VisualizerBtn.SetIcon(theme.VisibilityOffIcon())
+	} else {
+		p.UI.Visualizer.Hide()
+		p.UI.VisualizerBtn.SetIcon(theme.VisibilityIcon())
+	}
+}
+
+func (p *Player) updateSkipButtons() {
+	if p.Playlist.playingIndex == -1 {
+		return
+	}
+	if p.Playlist.playingIndex == 0 {
+		p.UI.PrevBtn.Disable()
+	} else {
+		p.UI.PrevBtn.Enable()
+	}
+	if p.Playlist.playingIndex == len(p.Playlist.songs)-1 {
+		p.UI.NextBtn.Disable()
+	} else {
+		p.UI.NextBtn.Enable()
 	}
+	p.UI.PrevBtn.Refresh()
+	p.UI.NextBtn.Refresh()
 }
 
 func formatTime(floatSeconds float64) string {
@@ -1,38 +1,448 @@
 package player
 
 import (
+	"context"
+	"fmt"
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
 type song struct {
 	path string
 	name string
+	// descriptive fields from imported playlist file, empty when unknown
+	title      string
+	artist     string
+	album      string
+	track      int
+	duration   time.Duration
+	extensions []string
+	// range of the virtual track inside the file, end is 0 when the track last until the end of file
+	start time.Duration
+	end   time.Duration
+}
+
+func (s song) virtual() bool {
+	return s.start > 0 || s.end > 0
+}
+
+func (s song) is(other song) bool {
+	return s.path == other.path && s.start == other.start && s.end == other.end
 }
 
 type Playlist struct {
-	songs        []song
-	playingIndex int
-	UI           struct {
+	name           string
+	songs          []song
+	playingIndex   int
+	tab            *container.TabItem
+	sorted         bool // whether the songs are in the order of sortBy
+	sortBy         column
+	sortDescending bool
+	smart          *smartQuery // songs come from the library by the rules, nil for normal playlist
+	filter         string
+	visible        []int   // index of the songs matching filter, nil when not filtered
+	rowHeight      float32 // height of a row of the list, measured on the first drop
+	UI             struct {
 		ImportFromFileBtn *widget.Button
 		ImportFromDirBtn  *widget.Button
+		ImportPlaylistBtn *widget.Button
+		ExportPlaylistBtn *widget.Button
+		SaveBtn           *widget.Button
+		RulesBtn          *widget.Button
+		Search            *widget.Entry
+		Header            []*widget.Button
 		List              *widget.List
-		entries           []entry // since can't get data from *widget.List, need to use another list to keep track of current
+		Footer            *widget.Label
 	}
 }
 
 type entry struct {
-	*widget.Label
-	onDoubleTapped func(audioPath string)
+	widget.BaseWidget
+	labels         []*widget.Label
+	onDoubleTapped func(s song)
 	song
 	index int
 }
 
+func newEntry() *entry {
+	e := &entry{}
+	for range columnNames {
+		label := widget.NewLabel("")
+		label.Truncation = fyne.TextTruncateEllipsis
+		e.labels = append(e.labels, label)
+	}
+	e.labels[trackColumn].Alignment = fyne.TextAlignTrailing
+	e.labels[durationColumn].Alignment = fyne.TextAlignTrailing
+	e.labels[playsColumn].Alignment = fyne.TextAlignTrailing
+	e.labels[skipsColumn].Alignment = fyne.TextAlignTrailing
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *entry) CreateRenderer() fyne.WidgetRenderer {
+	objects := make([]fyne.CanvasObject, len(e.labels))
+	for i, label := range e.labels {
+		objects[i] = label
+	}
+	return widget.NewSimpleRenderer(container.New(columnLayout{}, objects...))
+}
+
 func (e *entry) DoubleTapped(*fyne.PointEvent) {
-	e.onDoubleTapped(e.song.path)
+	e.onDoubleTapped(e.song)
 }
 
 func (e *entry) Cursor() desktop.Cursor {
 	return desktop.PointerCursor
 }
+
+func (p *Player) newPlaylist(name string, songs []song) *Playlist {
+	pl := &Playlist{name: name, songs: songs, playingIndex: -1}
+	window := p.window
+	pl.UI.ImportFromFileBtn = widget.NewButtonWithIcon("file", theme.ContentAddIcon(), func() {
+		dialog := dialog.NewFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil || file == nil {
+				log.Println("dialog.NewFolderOpen", err)
+				return
+			}
+			defer file.Close()
+			if file.URI().Extension() == ".cue" {
+				items, err := readPlaylist(file, file.URI().Path())
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				p.importItems(pl, "Import "+file.URI().Name(), items)
+				return
+			}
+			newSong := song{path: file.URI().Path(), name: file.URI().Name()}
+			if err := isValidAudio(newSong); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if index := slices.IndexFunc(pl.songs, func(s song) bool {
+				return s.is(newSong)
+			}); index != -1 {
+				dialog.ShowError(fmt.Errorf("%q already exist!", newSong.name), window)
+				return
+			}
+			pl.songs = append(pl.songs, newSong)
+			p.refreshPlaylist(pl)
+			p.updateSkipButtons()
+		}, window)
+		windowSize := window.Canvas().Size()
+		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+		dialog.Show()
+	})
+	pl.UI.ImportFromDirBtn = widget.NewButtonWithIcon("directory", theme.ContentAddIcon(), func() {
+		dialog := dialog.NewFolderOpen(func(files fyne.ListableURI, err error) {
+			if err != nil || files == nil {
+				log.Println("dialog.NewFolderOpen", err)
+				return
+			}
+			followSymlinks := widget.NewCheck("", nil)
+			followSymlinks.SetChecked(p.scan.followSymlinks)
+			depths := []string{"Unlimited", "1", "2", "3", "4", "5", "6", "7", "8"}
+			depth := widget.NewSelect(depths, nil)
+			depth.SetSelectedIndex(p.scan.maxDepth)
+			dialog.ShowForm("Import "+files.Name(), "Import", "Cancel", []*widget.FormItem{
+				widget.NewFormItem("Follow symlinks", followSymlinks),
+				widget.NewFormItem("Depth", depth),
+			}, func(confirm bool) {
+				if !confirm {
+					return
+				}
+				p.scan = scanOptions{followSymlinks.Checked, depth.SelectedIndex()}
+				root, options := files.Path(), p.scan
+				p.startImport(pl, "Import "+files.Name(), len(pl.songs), true, false, func(ctx context.Context) ([]playlistItem, []string, error) {
+					return collectDir(ctx, root, options)
+				})
+			}, window)
+		}, window)
+		windowSize := window.Canvas().Size()
+		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+		dialog.Show()
+	})
+	pl.UI.ImportPlaylistBtn = widget.NewButtonWithIcon("playlist", theme.FolderOpenIcon(), func() {
+		dialog := dialog.NewFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil || file == nil {
+				log.Println("dialog.NewFileOpen", err)
+				return
+			}
+			defer file.Close()
+			items, err := readPlaylist(file, file.URI().Path())
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			p.importItems(pl, "Import "+file.URI().Name(), items)
+		}, window)
+		dialog.SetFilter(storage.NewExtensionFileFilter(playlistFileExtensions))
+		windowSize := window.Canvas().Size()
+		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+		dialog.Show()
+	})
+	pl.UI.ExportPlaylistBtn = widget.NewButtonWithIcon("export", theme.UploadIcon(), func() {
+		dialog := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+			if err != nil || file == nil {
+				log.Println("dialog.NewFileSave", err)
+				return
+			}
+			songs := slices.Clone(pl.songs)
+			go func() { // the untagged songs are read from their files
+				defer file.Close()
+				items, err := playlistItems(songs)
+				if err == nil {
+					err = writePlaylist(file, file.URI().Path(), items)
+				}
+				if err != nil {
+					fyne.Do(func() {
+						dialog.ShowError(err, window)
+					})
+				}
+			}()
+		}, window)
+		dialog.SetFilter(storage.NewExtensionFileFilter(exportPlaylistExtensions))
+		if pl.name != "" {
+			dialog.SetFileName(pl.name + ".m3u8")
+		} else {
+			dialog.SetFileName("playlist.m3u8")
+		}
+		windowSize := window.Canvas().Size()
+		dialog.Resize(fyne.NewSize(windowSize.Width*0.8, windowSize.Height*0.8))
+		dialog.Show()
+	})
+	pl.UI.SaveBtn = widget.NewButtonWithIcon("save", theme.DocumentSaveIcon(), func() {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(pl.name)
+		nameEntry.Validator = validatePlaylistName
+		dialog.ShowForm("Save playlist", "Save", "Cancel", []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if err := savePlaylist(nameEntry.Text, pl.songs, pl.smart); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			pl.name = nameEntry.Text
+			pl.tab.Text = pl.name
+			p.UI.Playlists.Refresh()
+			p.refreshSavedPlaylists()
+		}, window)
+	})
+	pl.UI.RulesBtn = widget.NewButtonWithIcon("rules", theme.SettingsIcon(), func() {
+		p.showSmartEditor("Edit "+pl.tab.Text, pl.name, false, *pl.smart, func(_ string, query smartQuery) {
+			p.setSmart(pl, &query)
+			if pl.name == "" {
+				return
+			}
+			if err := savePlaylist(pl.name, pl.songs, pl.smart); err != nil {
+				dialog.ShowError(err, window)
+			}
+		})
+	})
+	pl.UI.RulesBtn.Hide()
+	pl.UI.List = widget.NewList(
+		func() int {
+			return pl.rowCount()
+		},
+		func() fyne.CanvasObject {
+			e := newEntry()
+			e.onDoubleTapped = func(s song) {
+				p.playAt(pl, e.index)
+			}
+			return e
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			e := o.(*entry)
+			e.index = pl.songIndex(i)
+			e.song = pl.songs[e.index]
+			info := p.songInfo(e.song)
+			e.labels[trackColumn].SetText("")
+			if info.track > 0 {
+				e.labels[trackColumn].SetText(fmt.Sprint(info.track))
+			}
+			e.labels[titleColumn].SetText(info.title)
+			e.labels[artistColumn].SetText(info.artist)
+			e.labels[albumColumn].SetText(info.album)
+			e.labels[durationColumn].SetText("")
+			if info.duration > 0 {
+				e.labels[durationColumn].SetText(formatTime(info.duration.Seconds()))
+			}
+			e.labels[playsColumn].SetText(fmt.Sprint(info.plays))
+			e.labels[lastPlayedColumn].SetText(formatLastPlayed(info.lastPlayed, time.Now()))
+			e.labels[skipsColumn].SetText(fmt.Sprint(info.skips))
+			for _, label := range e.labels {
+				if e.index == pl.playingIndex {
+					label.Importance = widget.HighImportance
+				} else {
+					label.Importance = widget.MediumImportance
+				}
+				label.Refresh()
+			}
+		})
+	header := make([]fyne.CanvasObject, len(columnNames))
+	for i, name := range columnNames {
+		by := column(i)
+		button := widget.NewButton(name, func() {
+			p.sortPlaylist(pl, by)
+		})
+		button.Importance = widget.LowImportance
+		button.IconPlacement = widget.ButtonIconTrailingText
+		pl.UI.Header = append(pl.UI.Header, button)
+		header[i] = button
+	}
+	pl.UI.Search = widget.NewEntry()
+	pl.UI.Search.SetPlaceHolder("Search title, artist, album or filename")
+	pl.UI.Search.OnChanged = func(text string) {
+		pl.filter = text
+		p.refreshPlaylist(pl)
+		pl.UI.List.ScrollToTop()
+	}
+	pl.UI.Search.OnSubmitted = func(string) {
+		if pl.rowCount() > 0 {
+			p.playAt(pl, pl.songIndex(0))
+		}
+	}
+	pl.UI.Footer = widget.NewLabel("")
+	p.refreshFooter(pl)
+	title := name
+	if title == "" {
+		title = "Untitled"
+	}
+	pl.tab = container.NewTabItem(title, container.NewBorder(
+		container.NewVBox(
+			container.NewHBox(layout.NewSpacer(), pl.UI.ImportFromFileBtn, pl.UI.ImportFromDirBtn, pl.UI.ImportPlaylistBtn, pl.UI.ExportPlaylistBtn, pl.UI.RulesBtn, pl.UI.SaveBtn, layout.NewSpacer()),
+			widget.NewSeparator(),
+			pl.UI.Search,
+			container.New(columnLayout{}, header...),
+		),
+		container.NewVBox(widget.NewSeparator(), pl.UI.Footer),
+		nil,
+		nil,
+		pl.UI.List,
+	))
+	p.playlists = append(p.playlists, pl)
+	return pl
+}
+
+// playAt play the song at index of the playlist, switching the playing playlist when needed
+func (p *Player) playAt(pl *Playlist, index int) {
+	if p.Playlist == pl && pl.playingIndex == index {
+		return
+	}
+	if p.Playlist != pl {
+		p.Playlist.playingIndex = -1
+		p.Playlist.UI.List.Refresh()
+		p.Playlist = pl
+	}
+	p.play(index)
+}
+
+// itemSong name the song by the title from the playlist file if there is, otherwise by the filename
+func itemSong(item playlistItem) song {
+	newSong := song{
+		path:       item.path,
+		name:       filepath.Base(item.path),
+		title:      item.title,
+		artist:     item.artist,
+		album:      item.album,
+		track:      item.track,
+		extensions: item.extensions,
+		start:      item.start,
+		end:        item.end,
+	}
+	if item.duration > 0 {
+		newSong.duration = item.duration
+	}
+	if item.title != "" {
+		newSong.name = item.title
+		if item.artist != "" {
+			newSong.name = item.artist + " - " + item.title
+		}
+	}
+	return newSong
+}
+
+// clear remove every song, the playing song keep playing but is no longer in the playlist
+func (pl *Playlist) clear() {
+	pl.songs = nil
+	pl.playingIndex = -1
+	pl.sorted = false
+}
+
+// insert the songs not already in the playlist at index, keeping playingIndex on the same song, return the count inserted
+func (pl *Playlist) insert(at int, songs []song) int {
+	var newSongs []song
+	for _, newSong := range songs {
+		exist := slices.ContainsFunc(pl.songs, newSong.is) || slices.ContainsFunc(newSongs, newSong.is)
+		if !exist {
+			newSongs = append(newSongs, newSong)
+		}
+	}
+	pl.songs = slices.Insert(pl.songs, at, newSongs...)
+	if len(newSongs) > 0 {
+		pl.sorted = false
+	}
+	if pl.playingIndex >= at {
+		pl.playingIndex += len(newSongs)
+	}
+	return len(newSongs)
+}
+
+// openPlaylist select the tab if the saved playlist is already opened, otherwise load it into a new tab
+func (p *Player) openPlaylist(name string) {
+	if index := slices.IndexFunc(p.playlists, func(pl *Playlist) bool {
+		return pl.name == name
+	}); index != -1 {
+		p.UI.Playlists.Select(p.playlists[index].tab)
+		return
+	}
+	songs, smart, err := loadPlaylist(name)
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	if smart != nil {
+		pl := p.newPlaylist(name, nil)
+		p.setSmart(pl, smart)
+		p.UI.Playlists.Append(pl.tab)
+		p.UI.Playlists.Select(pl.tab)
+		return
+	}
+	var missing []string
+	songs = slices.DeleteFunc(songs, func(s song) bool {
+		if _, err := os.Stat(s.path); err != nil {
+			missing = append(missing, s.name)
+			return true
+		}
+		return false
+	})
+	pl := p.newPlaylist(name, songs)
+	p.UI.Playlists.Append(pl.tab)
+	p.UI.Playlists.Select(pl.tab)
+	if len(missing) > 0 {
+		dialog.ShowError(fmt.Errorf("%d missing file(s) skipped: %s", len(missing), strings.Join(missing, ", ")), p.window)
+	}
+}
+
+func (p *Player) refreshSavedPlaylists() {
+	names, err := savedPlaylists()
+	if err != nil {
+		log.Println("savedPlaylists", err)
+		return
+	}
+	p.saved = names
+	p.UI.SavedPlaylists.Refresh()
+}
@@ -0,0 +1,313 @@
+package player
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedPlaylistFormat = errors.New("playlist file: unsupported format")
+	ErrVirtualTrack              = errors.New("playlist file: virtual track can only be written as its cue sheet")
+	ErrNoCueSheet                = errors.New("playlist file: no cue sheet found for the virtual track")
+)
+
+var (
+	playlistFileExtensions   = []string{".m3u", ".m3u8", ".pls", ".xspf", ".cue"}
+	exportPlaylistExtensions = []string{".m3u", ".m3u8", ".pls", ".xspf"} // cue sheet describe a single file album, so can only be imported
+)
+
+// playlistItem is a track entry as described by a playlist file, duration is -1 when unknown
+type playlistItem struct {
+	path       string
+	title      string
+	artist     string
+	album      string
+	track      int // 0 when unknown
+	duration   time.Duration
+	extensions []string // raw xml of the xspf track elements unknown to the player, kept for round trip
+	start      time.Duration
+	end        time.Duration
+}
+
+// readPlaylist parse the playlist file content from r, path decide the format and the base of relative entries
+func readPlaylist(r io.Reader, path string) ([]playlistItem, error) {
+	base := filepath.Dir(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		items, err := readM3U(r, base)
+		return expandCueSheets(items), err
+	case ".pls":
+		items, err := readPLS(r, base)
+		return expandCueSheets(items), err
+	case ".xspf":
+		items, err := readXSPF(r, base)
+		return expandCueSheets(items), err
+	case ".cue":
+		return readCUE(r, base)
+	}
+	return nil, ErrUnsupportedPlaylistFormat
+}
+
+// writePlaylist write the items to w in the format of path, virtual tracks should be replaced by their cue sheet before
+func writePlaylist(w io.Writer, path string, items []playlistItem) error {
+	if slices.ContainsFunc(items, func(item playlistItem) bool { return item.start > 0 || item.end > 0 }) {
+		return ErrVirtualTrack
+	}
+	base := filepath.Dir(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		return writeM3U(w, base, items)
+	case ".pls":
+		return writePLS(w, base, items)
+	case ".xspf":
+		return writeXSPF(w, base, items)
+	}
+	return ErrUnsupportedPlaylistFormat
+}
+
+/*
+#EXTM3U
+#EXTINF:<seconds>,<title>
+<path>
+*/
+func readM3U(r io.Reader, base string) ([]playlistItem, error) {
+	var (
+		items []playlistItem
+		next  = playlistItem{duration: -1}
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			// attributes like tvg-id="..." may follow the duration
+			info, _, _ = strings.Cut(info, " ")
+			if seconds, err := strconv.ParseFloat(info, 64); err == nil && seconds >= 0 {
+				next.duration = time.Duration(seconds * float64(time.Second))
+			}
+			next.title = strings.TrimSpace(title)
+		case strings.HasPrefix(line, "#"):
+		default:
+			next.path = resolvePlaylistPath(base, line)
+			items = append(items, next)
+			next = playlistItem{duration: -1}
+		}
+	}
+	return items, scanner.Err()
+}
+
+func writeM3U(w io.Writer, base string, items []playlistItem) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, item := range items {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", durationSeconds(item.duration), item.displayTitle())
+		fmt.Fprintln(bw, relativePlaylistPath(base, item.path))
+	}
+	return bw.Flush()
+}
+
+/*
+[playlist]
+File<n>=<path>
+Title<n>=<title>
+Length<n>=<seconds>
+NumberOfEntries=<count>
+Version=2
+*/
+func readPLS(r io.Reader, base string) ([]playlistItem, error) {
+	keyFormat := regexp.MustCompile(`^(?i)(file|title|length)(\d+)$`)
+	entries := map[int]*playlistItem{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff")), "=")
+		if !found {
+			continue
+		}
+		match := keyFormat.FindStringSubmatch(strings.TrimSpace(key))
+		if match == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(match[2])
+		if entries[index] == nil {
+			entries[index] = &playlistItem{duration: -1}
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(match[1]) {
+		case "file":
+			entries[index].path = resolvePlaylistPath(base, value)
+		case "title":
+			entries[index].title = value
+		case "length":
+			if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+				entries[index].duration = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var indexes []int
+	for index := range entries {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	var items []playlistItem
+	for _, index := range indexes {
+		if entries[index].path != "" {
+			items = append(items, *entries[index])
+		}
+	}
+	return items, nil
+}
+
+func writePLS(w io.Writer, base string, items []playlistItem) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	for i, item := range items {
+		fmt.Fprintf(bw, "File%d=%s\n", i+1, relativePlaylistPath(base, item.path))
+		if title := item.displayTitle(); title != "" {
+			fmt.Fprintf(bw, "Title%d=%s\n", i+1, title)
+		}
+		fmt.Fprintf(bw, "Length%d=%d\n", i+1, durationSeconds(item.duration))
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(items))
+	fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
+
+// resolvePlaylistPath resolve entry relative to the directory of the playlist file, file:// uri is also accepted
+func resolvePlaylistPath(base, location string) string {
+	if strings.HasPrefix(location, "file://") {
+		if u, err := url.Parse(location); err == nil {
+			return filepath.FromSlash(u.Path)
+		}
+	}
+	location = filepath.FromSlash(strings.ReplaceAll(location, `\`, "/"))
+	if filepath.IsAbs(location) {
+		return filepath.Clean(location)
+	}
+	return filepath.Join(base, location)
+}
+
+func relativePlaylistPath(base, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func durationSeconds(d time.Duration) int64 {
+	if d < 0 {
+		return -1
+	}
+	return int64(d.Round(time.Second).Seconds())
+}
+
+func (item playlistItem) displayTitle() string {
+	if item.artist != "" && item.title != "" {
+		return item.artist + " - " + item.title
+	}
+	return item.title
+}
+
+// playlistItems describe songs for exporting, fields missing from the song come from the audio itself, so call it in background.
+// Consecutive virtual tracks of a file are written once as their cue sheet, which is expanded again when the playlist is read
+func playlistItems(songs []song) ([]playlistItem, error) {
+	var items []playlistItem
+	sheets := map[string]string{} // cue sheet by audio
+	for _, s := range songs {
+		if s.virtual() {
+			sheet, found := sheets[s.path]
+			if !found {
+				sheet = findCueSheet(s.path)
+				sheets[s.path] = sheet
+			}
+			if sheet == "" {
+				return nil, fmt.Errorf("%w: %s", ErrNoCueSheet, s.path)
+			}
+			if len(items) == 0 || items[len(items)-1].path != sheet {
+				items = append(items, playlistItem{path: sheet, duration: -1})
+			}
+			continue
+		}
+		item := playlistItem{
+			path:       s.path,
+			title:      s.title,
+			artist:     s.artist,
+			album:      s.album,
+			track:      s.track,
+			duration:   s.duration,
+			extensions: s.extensions,
+		}
+		if item.title == "" {
+			item.title = strings.TrimSuffix(s.name, filepath.Ext(s.name))
+			if album, err := readTags(s.path); err == nil && album.Title != "" && album.Title != "Unknown Title" {
+				item.title = album.Title
+				if album.Artist != "Unknown Artist" {
+					item.artist = album.Artist
+				}
+			}
+		}
+		if item.duration <= 0 {
+			item.duration = -1
+			if d, err := audioDuration(s.path); err == nil {
+				item.duration = d
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// findCueSheet look for the cue sheet describing the audio next to it, empty when there is none
+func findCueSheet(audio string) string {
+	sheets, _ := filepath.Glob(filepath.Join(filepath.Dir(audio), "*.cue"))
+	for _, sheet := range sheets {
+		f, err := os.Open(sheet)
+		if err != nil {
+			continue
+		}
+		items, err := readCUE(f, filepath.Dir(sheet))
+		f.Close()
+		if err == nil && slices.ContainsFunc(items, func(item playlistItem) bool { return item.path == audio }) {
+			return sheet
+		}
+	}
+	return ""
+}
+
+// expandCueSheets replace the cue sheets listed by a playlist with their virtual tracks, an unreadable sheet is left to be rejected
+func expandCueSheets(items []playlistItem) []playlistItem {
+	var expanded []playlistItem
+	for _, item := range items {
+		if strings.ToLower(filepath.Ext(item.path)) != ".cue" {
+			expanded = append(expanded, item)
+			continue
+		}
+		f, err := os.Open(item.path)
+		if err != nil {
+			expanded = append(expanded, item)
+			continue
+		}
+		sheet, err := readCUE(f, filepath.Dir(item.path))
+		f.Close()
+		if err != nil {
+			expanded = append(expanded, item)
+			continue
+		}
+		expanded = append(expanded, sheet...)
+	}
+	return expanded
+}
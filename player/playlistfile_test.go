@@ -0,0 +1,59 @@
+package player
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlaylistFile(t *testing.T) {
+	base := filepath.FromSlash("/music/lists")
+
+	t.Run("read extended m3u", func(t *testing.T) {
+		content := "\ufeff#EXTM3U\n#EXTINF:215,Soft Tags - They Live By Night\n../album/01.mp3\n\n# comment\n/abs/02.wav\n#EXTINF:-1,Stream\nfile:///abs/03%20x.mp3\n"
+		items, err := readPlaylist(strings.NewReader(content), filepath.Join(base, "a.m3u8"))
+		assert.NoError(t, err)
+		assert.Equal(t, []playlistItem{
+			{path: filepath.FromSlash("/music/album/01.mp3"), title: "Soft Tags - They Live By Night", duration: 215 * time.Second},
+			{path: filepath.FromSlash("/abs/02.wav"), duration: -1},
+			{path: filepath.FromSlash("/abs/03 x.mp3"), title: "Stream", duration: -1},
+		}, items)
+	})
+	t.Run("write extended m3u", func(t *testing.T) {
+		got := &bytes.Buffer{}
+		err := writePlaylist(got, filepath.Join(base, "a.m3u"), []playlistItem{
+			{path: filepath.Join(base, "sub", "01.mp3"), title: "Title", artist: "Artist", duration: 61 * time.Second},
+			{path: filepath.FromSlash("/other/02.wav"), duration: -1},
+			{path: filepath.Join(base, "..dots.mp3"), duration: -1},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "#EXTM3U\n#EXTINF:61,Artist - Title\nsub/01.mp3\n#EXTINF:-1,\n"+filepath.FromSlash("/other/02.wav")+"\n#EXTINF:-1,\n..dots.mp3\n", got.String())
+	})
+	t.Run("read pls", func(t *testing.T) {
+		content := "[playlist]\nFile2=/abs/02.wav\nFile1=01.mp3\nTitle1=First\nLength1=30\nNumberOfEntries=2\nVersion=2\n"
+		items, err := readPlaylist(strings.NewReader(content), filepath.Join(base, "a.pls"))
+		assert.NoError(t, err)
+		assert.Equal(t, []playlistItem{
+			{path: filepath.Join(base, "01.mp3"), title: "First", duration: 30 * time.Second},
+			{path: filepath.FromSlash("/abs/02.wav"), duration: -1},
+		}, items)
+	})
+	t.Run("pls round trip", func(t *testing.T) {
+		want := []playlistItem{
+			{path: filepath.Join(base, "01.mp3"), title: "First", duration: 30 * time.Second},
+			{path: filepath.Join(base, "02.wav"), title: "Second", duration: -1},
+		}
+		buf := &bytes.Buffer{}
+		assert.NoError(t, writePlaylist(buf, filepath.Join(base, "a.pls"), want))
+		got, err := readPlaylist(buf, filepath.Join(base, "a.pls"))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := readPlaylist(strings.NewReader(""), "a.txt")
+		assert.EqualError(t, err, ErrUnsupportedPlaylistFormat.Error())
+	})
+}
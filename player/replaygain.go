@@ -0,0 +1,120 @@
+package player
+
+import (
+	"errors"
+	"github.com/gorgemul/musicplayer/engine"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidReplayGain = errors.New("replaygain: pre-amp and fallback gain should be numbers of dB")
+
+// ReplayGain is read from the REPLAYGAIN_* tags or measured by the loudness scan, a peak is 0 when not tagged
+type ReplayGain struct {
+	TrackGain    float64 `json:"trackGain"` // dB
+	TrackPeak    float64 `json:"trackPeak"` // 1 is full scale
+	AlbumGain    float64 `json:"albumGain"`
+	AlbumPeak    float64 `json:"albumPeak"`
+	HasTrackGain bool    `json:"hasTrackGain"`
+	HasAlbumGain bool    `json:"hasAlbumGain"`
+}
+
+// set the value of the tag named key, other tags and values which are not numbers are ignored
+func (rg *ReplayGain) set(key, value string) {
+	value = strings.TrimSpace(value)
+	if len(value) > 2 && strings.EqualFold(value[len(value)-2:], "db") {
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return
+	}
+	switch strings.ToUpper(key) {
+	case "REPLAYGAIN_TRACK_GAIN":
+		rg.TrackGain, rg.HasTrackGain = number, true
+	case "REPLAYGAIN_TRACK_PEAK":
+		rg.TrackPeak = number
+	case "REPLAYGAIN_ALBUM_GAIN":
+		rg.AlbumGain, rg.HasAlbumGain = number, true
+	case "REPLAYGAIN_ALBUM_PEAK":
+		rg.AlbumPeak = number
+	}
+}
+
+type gainMode string
+
+const (
+	gainOff   gainMode = "off"
+	gainTrack gainMode = "track"
+	gainAlbum gainMode = "album"
+	gainAuto  gainMode = "auto" // album gain when the album is played in order, track gain otherwise
+)
+
+var gainModes = []gainMode{gainOff, gainTrack, gainAlbum, gainAuto}
+
+type replayGainSettings struct {
+	Mode            gainMode `json:"mode"`
+	Preamp          float64  `json:"preamp"`          // dB added to the tagged gain
+	Fallback        float64  `json:"fallback"`        // dB for the files without gain tags
+	PreventClipping bool     `json:"preventClipping"` // lower the gain so the tagged peak does not clip
+}
+
+// gain in dB to play the tagged file at, the track gain and album gain stand in for each other when only one is tagged
+func (s replayGainSettings) gain(rg ReplayGain, album bool) float64 {
+	if s.Mode == gainOff {
+		return 0
+	}
+	gain, peak, tagged := rg.TrackGain, rg.TrackPeak, rg.HasTrackGain
+	if rg.HasAlbumGain && (album || !tagged) {
+		gain, peak, tagged = rg.AlbumGain, rg.AlbumPeak, true
+	}
+	if !tagged {
+		return s.Fallback
+	}
+	gain += s.Preamp
+	if s.PreventClipping && peak > 0 {
+		gain = min(gain, -20*math.Log10(peak))
+	}
+	return gain
+}
+
+// inAlbumOrder tell whether the track is played within its album:
+// the track before or after it in the queue is the previous or next of the same album, or of the same cue sheet
+func inAlbumOrder(queue []engine.Track, index int, current Album, tags func(path string) Album) bool {
+	for _, step := range []int{-1, 1} {
+		i := index + step
+		if i < 0 || i >= len(queue) {
+			continue
+		}
+		if queue[i].Path == queue[index].Path {
+			return true
+		}
+		other := tags(queue[i].Path)
+		if current.AlbumTitle != "" && other.AlbumTitle == current.AlbumTitle && other.Disc == current.Disc && other.Track == current.Track+step {
+			return true
+		}
+	}
+	return false
+}
+
+// replayGainFunc give the engine the gain of the tracks by the settings, reading their tags.
+// The gains measured by the loudness scan stand in for the files without gain tags, measured is not modified afterward
+func replayGainFunc(s replayGainSettings, measured map[string]ReplayGain) engine.GainFunc {
+	if s.Mode == gainOff {
+		return nil
+	}
+	tags := func(path string) Album {
+		album, _ := readTags(path) // untagged or unreadable play at the fallback gain
+		return album
+	}
+	return func(queue []engine.Track, index int) float64 {
+		current := tags(queue[index].Path)
+		album := s.Mode == gainAlbum || (s.Mode == gainAuto && inAlbumOrder(queue, index, current, tags))
+		rg := current.ReplayGain
+		if measured, ok := measured[queue[index].Path]; ok && !rg.HasTrackGain && !rg.HasAlbumGain {
+			rg = measured
+		}
+		return s.gain(rg, album)
+	}
+}
@@ -0,0 +1,107 @@
+package player
+
+import (
+	"encoding/binary"
+	"github.com/gorgemul/musicplayer/engine"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayGain(t *testing.T) {
+	dir := t.TempDir()
+	txxx := func(content string) string {
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(content)))
+		return "TXXX" + string(size) + "\x00\x00" + content
+	}
+	utf16 := func(s string) string {
+		encoded := "\xff\xfe"
+		for _, r := range s {
+			encoded += string([]byte{byte(r), 0})
+		}
+		return encoded
+	}
+	frames := txxx("\x00REPLAYGAIN_TRACK_GAIN\x00-7.25 dB") + txxx("\x00replaygain_track_peak\x000.988547\x00") +
+		txxx("\x01"+utf16("REPLAYGAIN_ALBUM_GAIN")+"\x00\x00"+utf16("-6.5 dB")) + txxx("\x00MusicBrainz Album Id\x00abc")
+	tag := "ID3\x03\x00\x00\x00" + string([]byte{byte(len(frames) >> 14 & 0x7f), byte(len(frames) >> 7 & 0x7f), byte(len(frames) & 0x7f)}) + frames
+	mp3 := filepath.Join(dir, "a.mp3")
+	assert.NoError(t, os.WriteFile(mp3, []byte(tag+"audio frames"), 0644))
+
+	t.Run("id3 TXXX frames", func(t *testing.T) {
+		album, err := readTags(mp3)
+		assert.NoError(t, err)
+		assert.Equal(t, ReplayGain{TrackGain: -7.25, TrackPeak: 0.988547, AlbumGain: -6.5, HasTrackGain: true, HasAlbumGain: true}, album.ReplayGain)
+	})
+	t.Run("vorbis comments", func(t *testing.T) {
+		var rg ReplayGain
+		comments := map[string]string{"REPLAYGAIN_ALBUM_GAIN": "+1.20 dB", "REPLAYGAIN_ALBUM_PEAK": "1.1", "REPLAYGAIN_TRACK_GAIN": "loud"}
+		for key, value := range comments {
+			rg.set(key, value)
+		}
+		assert.Equal(t, ReplayGain{AlbumGain: 1.2, AlbumPeak: 1.1, HasAlbumGain: true}, rg)
+	})
+	t.Run("untagged format", func(t *testing.T) {
+		wav := filepath.Join(dir, "a.wav")
+		assert.NoError(t, os.WriteFile(wav, []byte("RIFF"), 0644))
+		album, err := readTags(wav)
+		assert.NoError(t, err)
+		assert.Equal(t, ReplayGain{}, album.ReplayGain)
+	})
+
+	rg := ReplayGain{TrackGain: -8, TrackPeak: 0.5, AlbumGain: -5, AlbumPeak: 0.9, HasTrackGain: true, HasAlbumGain: true}
+	t.Run("modes", func(t *testing.T) {
+		assert.Equal(t, 0.0, replayGainSettings{Mode: gainOff, Fallback: -3}.gain(rg, true))
+		assert.Equal(t, -8.0, replayGainSettings{Mode: gainTrack}.gain(rg, false))
+		assert.Equal(t, -5.0, replayGainSettings{Mode: gainAlbum}.gain(rg, true))
+		assert.Equal(t, -4.0, replayGainSettings{Mode: gainTrack, Preamp: 4}.gain(rg, false))
+	})
+	t.Run("missing gains", func(t *testing.T) {
+		s := replayGainSettings{Mode: gainTrack, Fallback: -6, Preamp: 2}
+		assert.Equal(t, -6.0, s.gain(ReplayGain{}, false), "fallback without pre-amp")
+		assert.Equal(t, -3.0, s.gain(ReplayGain{AlbumGain: -5, HasAlbumGain: true}, false), "album gain stand in")
+		assert.Equal(t, -6.0, s.gain(ReplayGain{TrackGain: -8, HasTrackGain: true}, true), "track gain stand in")
+	})
+	t.Run("clipping prevention", func(t *testing.T) {
+		s := replayGainSettings{Mode: gainTrack, Preamp: 16, PreventClipping: true}
+		assert.InDelta(t, -20*math.Log10(0.5), s.gain(rg, false), 1e-9)
+		s.PreventClipping = false
+		assert.Equal(t, 8.0, s.gain(rg, false))
+		s.PreventClipping = true
+		assert.Equal(t, 16.0, s.gain(ReplayGain{HasTrackGain: true}, false), "no peak tagged")
+	})
+	t.Run("album in order", func(t *testing.T) {
+		tags := map[string]Album{
+			"1": {AlbumTitle: "A", Track: 1},
+			"2": {AlbumTitle: "A", Track: 2},
+			"3": {AlbumTitle: "A", Track: 3},
+			"x": {AlbumTitle: "B", Track: 4},
+		}
+		read := func(path string) Album { return tags[path] }
+		queue := func(paths ...string) []engine.Track {
+			var tracks []engine.Track
+			for _, path := range paths {
+				tracks = append(tracks, engine.Track{Path: path})
+			}
+			return tracks
+		}
+		assert.True(t, inAlbumOrder(queue("1", "2", "3"), 1, tags["2"], read))
+		assert.True(t, inAlbumOrder(queue("x", "2", "3"), 1, tags["2"], read))
+		assert.False(t, inAlbumOrder(queue("3", "2", "1"), 1, tags["2"], read), "reversed")
+		assert.False(t, inAlbumOrder(queue("x", "2", "x"), 1, tags["2"], read), "shuffled")
+		assert.False(t, inAlbumOrder(queue("2"), 0, tags["2"], read))
+		assert.True(t, inAlbumOrder([]engine.Track{{Path: "cue"}, {Path: "cue", Start: 1}}, 1, Album{}, read), "cue sheet")
+	})
+	t.Run("engine gain", func(t *testing.T) {
+		assert.Nil(t, replayGainFunc(replayGainSettings{Mode: gainOff}, nil))
+		gain := replayGainFunc(replayGainSettings{Mode: gainAuto, Fallback: -1}, nil)
+		assert.Equal(t, -7.25, gain([]engine.Track{{Path: mp3}}, 0))
+		assert.Equal(t, -1.0, gain([]engine.Track{{Path: filepath.Join(dir, "missing.mp3")}}, 0))
+		measured := map[string]ReplayGain{mp3: {TrackGain: -2, HasTrackGain: true}, filepath.Join(dir, "b.mp3"): {TrackGain: -3, HasTrackGain: true}}
+		gain = replayGainFunc(replayGainSettings{Mode: gainTrack, Fallback: -1}, measured)
+		assert.Equal(t, -7.25, gain([]engine.Track{{Path: mp3}}, 0), "tags first")
+		assert.Equal(t, -3.0, gain([]engine.Track{{Path: filepath.Join(dir, "b.mp3")}}, 0), "measured stand in")
+	})
+}
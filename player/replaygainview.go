@@ -0,0 +1,63 @@
+package player
+
+import (
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"strconv"
+	"strings"
+)
+
+func (p *Player) showReplayGain() {
+	var modes []string
+	for _, mode := range gainModes {
+		modes = append(modes, string(mode))
+	}
+	mode := widget.NewSelect(modes, nil)
+	mode.SetSelected(string(p.settings.ReplayGain.Mode))
+	preamp := widget.NewEntry()
+	preamp.SetText(strconv.FormatFloat(p.settings.ReplayGain.Preamp, 'f', -1, 64))
+	fallback := widget.NewEntry()
+	fallback.SetText(strconv.FormatFloat(p.settings.ReplayGain.Fallback, 'f', -1, 64))
+	preventClipping := widget.NewCheck("Lower the gain so the peak does not clip", nil)
+	preventClipping.SetChecked(p.settings.ReplayGain.PreventClipping)
+	items := []*widget.FormItem{
+		widget.NewFormItem("Mode", mode),
+		widget.NewFormItem("Pre-amp (dB)", preamp),
+		widget.NewFormItem("Fallback gain (dB)", fallback),
+		widget.NewFormItem("", preventClipping),
+	}
+	items[0].HintText = "auto use the album gain when the album is played in order"
+	items[2].HintText = "for the files without ReplayGain tags"
+	dialog.ShowForm("ReplayGain", "Save", "Cancel", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		s := p.settings
+		var preampErr, fallbackErr error
+		s.ReplayGain.Mode = gainMode(mode.Selected)
+		s.ReplayGain.Preamp, preampErr = strconv.ParseFloat(strings.TrimSpace(preamp.Text), 64)
+		s.ReplayGain.Fallback, fallbackErr = strconv.ParseFloat(strings.TrimSpace(fallback.Text), 64)
+		s.ReplayGain.PreventClipping = preventClipping.Checked
+		if preampErr != nil || fallbackErr != nil {
+			dialog.ShowError(ErrInvalidReplayGain, p.window)
+			return
+		}
+		if err := saveSettings(s); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		p.settings = s
+		p.applyReplayGain()
+	}, p.window)
+}
+
+// applyReplayGain give the engine the gains by the settings, the gains measured in the library stand in for missing tags
+func (p *Player) applyReplayGain() {
+	measured := map[string]ReplayGain{}
+	for path, track := range p.library.tracks {
+		if track.ReplayGain != nil {
+			measured[path] = *track.ReplayGain
+		}
+	}
+	p.engine.SetGain(replayGainFunc(p.settings.ReplayGain, measured))
+}
@@ -0,0 +1,191 @@
+package player
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedHistoryFormat = errors.New("history export: unsupported format")
+	ErrInvalidReportRange       = errors.New("report: range should be dates as 2006-01-02 with from not after to")
+)
+
+var exportHistoryExtensions = []string{".csv", ".json"}
+
+// exportedPlay is a history entry as exported, durations are seconds so that spreadsheet and other tools can read them
+type exportedPlay struct {
+	Time     time.Time `json:"time"`
+	Path     string    `json:"path"`
+	Start    float64   `json:"start"`
+	Title    string    `json:"title"`
+	Artist   string    `json:"artist"`
+	Album    string    `json:"album"`
+	Position float64   `json:"position"`
+	Duration float64   `json:"duration"`
+	Played   bool      `json:"played"`
+	Skipped  bool      `json:"skipped"`
+}
+
+var exportedPlayColumns = []string{"time", "path", "start", "title", "artist", "album", "position", "duration", "played", "skipped"}
+
+func exportedPlays(history []historyEntry, s settings) []exportedPlay {
+	plays := []exportedPlay{}
+	for _, e := range history {
+		plays = append(plays, exportedPlay{
+			Time:     e.Time,
+			Path:     e.Path,
+			Start:    e.Start.Seconds(),
+			Title:    e.Title,
+			Artist:   e.Artist,
+			Album:    e.Album,
+			Position: e.Position.Seconds(),
+			Duration: e.Duration.Seconds(),
+			Played:   e.played(s),
+			Skipped:  e.Skipped && !e.played(s),
+		})
+	}
+	return plays
+}
+
+// writeHistory export the history to w, the extension of path decide the format
+func writeHistory(w io.Writer, path string, history []historyEntry, s settings) error {
+	plays := exportedPlays(history, s)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return writeHistoryCSV(w, plays)
+	case ".json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plays)
+	}
+	return ErrUnsupportedHistoryFormat
+}
+
+func writeHistoryCSV(w io.Writer, plays []exportedPlay) error {
+	writer := csv.NewWriter(w)
+	writer.Write(exportedPlayColumns)
+	seconds := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 3, 64)
+	}
+	for _, play := range plays {
+		writer.Write([]string{
+			play.Time.Format(time.RFC3339),
+			play.Path,
+			seconds(play.Start),
+			play.Title,
+			play.Artist,
+			play.Album,
+			seconds(play.Position),
+			seconds(play.Duration),
+			strconv.FormatBool(play.Played),
+			strconv.FormatBool(play.Skipped),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// reportCount is a row of the top lists, ranked by plays then listening time
+type reportCount struct {
+	name      string
+	plays     int
+	listening time.Duration
+}
+
+type historyReport struct {
+	plays     int
+	listening time.Duration // total heard, including the part of skipped songs
+	artists   []reportCount
+	albums    []reportCount
+	tracks    []reportCount
+	hours     [24]time.Duration // listening by hour of day
+	weekdays  [7]time.Duration  // listening by day of week, sunday first like time.Weekday
+}
+
+// parseReportRange read the inclusive dates of the report, in the local time zone
+func parseReportRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(from), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidReportRange
+	}
+	end, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(to), time.Local)
+	if err != nil || end.Before(start) {
+		return time.Time{}, time.Time{}, ErrInvalidReportRange
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// buildReport summarize the entries started in [from, to), top lists keep the first top rows.
+// Hours and weekdays are of the time the song started, in the time zone of from
+func buildReport(history []historyEntry, s settings, from, to time.Time, top int) historyReport {
+	var report historyReport
+	artists, albums, tracks := map[string]*reportCount{}, map[string]*reportCount{}, map[string]*reportCount{}
+	count := func(counts map[string]*reportCount, key, name string, e historyEntry) {
+		if key == "" {
+			return
+		}
+		c := counts[key]
+		if c == nil {
+			c = &reportCount{name: name}
+			counts[key] = c
+		}
+		if e.played(s) {
+			c.plays++
+		}
+		c.listening += e.Position
+	}
+	for _, e := range history {
+		if e.Time.Before(from) || !e.Time.Before(to) {
+			continue
+		}
+		if e.played(s) {
+			report.plays++
+		}
+		report.listening += e.Position
+		started := e.Time.In(from.Location())
+		report.hours[started.Hour()] += e.Position
+		report.weekdays[started.Weekday()] += e.Position
+		title := e.Title
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(e.Path), filepath.Ext(e.Path))
+		}
+		if e.Artist != "" {
+			title += " - " + e.Artist
+		}
+		count(artists, e.Artist, e.Artist, e)
+		if e.Album != "" { // albums of the same title by other artists are told apart
+			album := e.Album
+			if e.Artist != "" {
+				album += " - " + e.Artist
+			}
+			count(albums, e.Artist+"\x00"+e.Album, album, e)
+		}
+		count(tracks, title, title, e)
+	}
+	report.artists = topCounts(artists, top)
+	report.albums = topCounts(albums, top)
+	report.tracks = topCounts(tracks, top)
+	return report
+}
+
+func topCounts(counts map[string]*reportCount, top int) []reportCount {
+	var sorted []reportCount
+	for _, c := range counts {
+		sorted = append(sorted, *c)
+	}
+	slices.SortFunc(sorted, func(a, b reportCount) int {
+		return cmp.Or(cmp.Compare(b.plays, a.plays), cmp.Compare(b.listening, a.listening), naturalCompare(a.name, b.name))
+	})
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	return sorted
+}
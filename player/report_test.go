@@ -0,0 +1,61 @@
+package player
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHistoryReport(t *testing.T) {
+	s := settings{PlayPercent: 50}
+	monday := time.Date(2024, 6, 3, 21, 0, 0, 0, time.UTC)
+	history := []historyEntry{
+		{Time: monday.AddDate(0, 0, -7), Path: "/old.mp3", Title: "Old", Artist: "B", Position: time.Minute, Duration: time.Minute},
+		{Time: monday, Path: "/a.mp3", Title: "A", Artist: "X", Album: "One", Position: 3 * time.Minute, Duration: 3 * time.Minute},
+		{Time: monday.Add(time.Hour), Path: "/a.mp3", Title: "A", Artist: "X", Album: "One", Position: 30 * time.Second, Duration: 3 * time.Minute, Skipped: true},
+		{Time: monday.Add(2 * time.Hour), Path: "/b.mp3", Title: "B", Artist: "Y", Album: "Two", Position: 2 * time.Minute, Duration: 2 * time.Minute},
+		{Time: monday.Add(26 * time.Hour), Path: "/c.mp3", Artist: "Y", Album: "Two", Position: 4 * time.Minute, Duration: 4 * time.Minute},
+		{Time: monday.Add(27 * time.Hour), Path: "/d.mp3", Title: "D", Artist: "Z", Album: "Two", Position: 10 * time.Second, Duration: 4 * time.Minute, Skipped: true},
+	}
+
+	t.Run("export csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, writeHistory(&buf, "/tmp/history.csv", history[1:3], s))
+		assert.Equal(t, "time,path,start,title,artist,album,position,duration,played,skipped\n"+
+			"2024-06-03T21:00:00Z,/a.mp3,0.000,A,X,One,180.000,180.000,true,false\n"+
+			"2024-06-03T22:00:00Z,/a.mp3,0.000,A,X,One,30.000,180.000,false,true\n", buf.String())
+	})
+	t.Run("export json", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, writeHistory(&buf, "/tmp/history.JSON", history[1:2], s))
+		assert.JSONEq(t, `[{"time":"2024-06-03T21:00:00Z","path":"/a.mp3","start":0,"title":"A","artist":"X","album":"One","position":180,"duration":180,"played":true,"skipped":false}]`, buf.String())
+		buf.Reset()
+		assert.NoError(t, writeHistory(&buf, "/tmp/history.json", nil, s))
+		assert.JSONEq(t, `[]`, buf.String())
+		assert.ErrorIs(t, writeHistory(&buf, "/tmp/history.txt", history, s), ErrUnsupportedHistoryFormat)
+	})
+	t.Run("report", func(t *testing.T) {
+		report := buildReport(history, s, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), 2)
+		assert.Equal(t, 3, report.plays)
+		assert.Equal(t, 9*time.Minute+40*time.Second, report.listening)
+		assert.Equal(t, []reportCount{{"Y", 2, 6 * time.Minute}, {"X", 1, 3*time.Minute + 30*time.Second}}, report.artists)
+		assert.Equal(t, []reportCount{{"Two - Y", 2, 6 * time.Minute}, {"One - X", 1, 3*time.Minute + 30*time.Second}}, report.albums, "the Two of Z apart")
+		assert.Equal(t, []reportCount{{"c - Y", 1, 4 * time.Minute}, {"A - X", 1, 3*time.Minute + 30*time.Second}}, report.tracks)
+		assert.Equal(t, 3*time.Minute, report.hours[21])
+		assert.Equal(t, 30*time.Second, report.hours[22])
+		assert.Equal(t, 6*time.Minute, report.hours[23])
+		assert.Equal(t, 5*time.Minute+30*time.Second, report.weekdays[time.Monday])
+		assert.Equal(t, 4*time.Minute, report.weekdays[time.Tuesday])
+	})
+	t.Run("range", func(t *testing.T) {
+		from, to, err := parseReportRange("2024-01-01", " 2024-12-31")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), from)
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), to)
+		_, _, err = parseReportRange("2024-12-31", "2024-01-01")
+		assert.ErrorIs(t, err, ErrInvalidReportRange)
+		_, _, err = parseReportRange("last year", "2024-01-01")
+		assert.ErrorIs(t, err, ErrInvalidReportRange)
+	})
+}
@@ -0,0 +1,144 @@
+package player
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type scanOptions struct {
+	followSymlinks bool
+	maxDepth       int // 0 means no limit, 1 only list the selected directory itself
+}
+
+// scanDir list the files under root recursively, symlinked directory is only visited once even if it loop back
+func scanDir(ctx context.Context, root string, options scanOptions) ([]string, error) {
+	var (
+		files   []string
+		visited = map[string]bool{}
+		walk    func(dir string, depth int) error
+	)
+	walk = func(dir string, depth int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if visited[real] {
+				return nil
+			}
+			visited[real] = true
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			isDir := entry.IsDir()
+			if entry.Type()&os.ModeSymlink != 0 {
+				if !options.followSymlinks {
+					continue
+				}
+				info, err := os.Stat(path)
+				if err != nil { // dangling link
+					continue
+				}
+				isDir = info.IsDir()
+			}
+			if !isDir {
+				files = append(files, path)
+				continue
+			}
+			if options.maxDepth == 0 || depth < options.maxDepth {
+				// unreadable sub directory should not abort the whole import, but cancel should
+				if err := walk(path, depth+1); err != nil && ctx.Err() != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root, 1); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// sortTracks order the tracks by directory, then by disc and track number when tagged (track > 0), by natural filename otherwise
+func sortTracks[T any](tracks []T, path func(T) string, number func(T) (disc, track int)) {
+	type key struct {
+		track T
+		path  string
+		dir   []string
+		disc  int
+		order int
+	}
+	keys := make([]key, len(tracks))
+	for i, track := range tracks {
+		keys[i] = key{track: track, path: path(track)}
+		keys[i].dir = strings.Split(filepath.Dir(keys[i].path), string(filepath.Separator))
+		keys[i].disc, keys[i].order = number(track)
+	}
+	slices.SortStableFunc(keys, func(a, b key) int {
+		if c := slices.CompareFunc(a.dir, b.dir, naturalCompare); c != 0 {
+			return c
+		}
+		if (a.order > 0) != (b.order > 0) {
+			if a.order > 0 {
+				return -1
+			}
+			return 1
+		}
+		if a.disc != b.disc {
+			return a.disc - b.disc
+		}
+		if a.order != b.order {
+			return a.order - b.order
+		}
+		return naturalCompare(filepath.Base(a.path), filepath.Base(b.path))
+	})
+	for i, k := range keys {
+		tracks[i] = k.track
+	}
+}
+
+// naturalCompare compare case insensitively, with digit runs compared by numeric value so "2" sort before "10"
+func naturalCompare(a, b string) int {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return int(ra[i]) - int(rb[j])
+		}
+		i++
+		j++
+	}
+	if c := (len(ra) - i) - (len(rb) - j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,103 @@
+package player
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"a.mp3", "Artist/Album/01.mp3", "Artist/Album/CD1/01.flac", "other/x.wav"} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+	assert.NoError(t, os.Symlink(filepath.Join(root, "other"), filepath.Join(root, "Artist", "link")))
+	assert.NoError(t, os.Symlink(root, filepath.Join(root, "other", "loop")))
+	relative := func(files []string) []string {
+		for i, file := range files {
+			rel, _ := filepath.Rel(root, file)
+			files[i] = filepath.ToSlash(rel)
+		}
+		return files
+	}
+
+	t.Run("recursive", func(t *testing.T) {
+		files, err := scanDir(context.Background(), root, scanOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Artist/Album/01.mp3", "Artist/Album/CD1/01.flac", "a.mp3", "other/x.wav"}, relative(files))
+	})
+	t.Run("depth limit", func(t *testing.T) {
+		files, err := scanDir(context.Background(), root, scanOptions{maxDepth: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a.mp3"}, relative(files))
+		files, err = scanDir(context.Background(), root, scanOptions{maxDepth: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Artist/Album/01.mp3", "a.mp3", "other/x.wav"}, relative(files))
+	})
+	t.Run("follow symlinks without looping", func(t *testing.T) {
+		files, err := scanDir(context.Background(), root, scanOptions{followSymlinks: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Artist/Album/01.mp3", "Artist/Album/CD1/01.flac", "Artist/link/x.wav", "a.mp3"}, relative(files))
+	})
+	t.Run("natural compare", func(t *testing.T) {
+		names := []string{"track10.mp3", "Track2.mp3", "track1.mp3", "track01b.mp3", "a.mp3"}
+		sortTracks(names, func(name string) string { return name }, func(string) (int, int) { return 0, 0 })
+		assert.Equal(t, []string{"a.mp3", "track1.mp3", "track01b.mp3", "Track2.mp3", "track10.mp3"}, names)
+	})
+	t.Run("sort by disc and track", func(t *testing.T) {
+		numbers := map[string][2]int{"b.mp3": {1, 2}, "c.mp3": {2, 1}, "d.mp3": {1, 1}}
+		paths := []string{"z/a.mp3", "b.mp3", "c.mp3", "untagged.mp3", "d.mp3"}
+		sortTracks(paths, func(path string) string { return path }, func(path string) (int, int) {
+			n := numbers[path]
+			return n[0], n[1]
+		})
+		assert.Equal(t, []string{"d.mp3", "b.mp3", "c.mp3", "untagged.mp3", "z/a.mp3"}, paths)
+	})
+}
+
+func TestTrackNumber(t *testing.T) {
+	dir := t.TempDir()
+	frame := func(id, content string) string {
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint32(size, uint32(len(content)+2))
+		return id + string(size) + "\x00\x00" + "\x00" + content + "\x00"
+	}
+	frames := frame("TRCK", "3/12") + frame("TPOS", "2/2")
+	tag := "ID3\x03\x00\x00\x00\x00\x00" + string(rune(len(frames))) + frames
+	mp3 := filepath.Join(dir, "a.mp3")
+	assert.NoError(t, os.WriteFile(mp3, []byte(tag+"audio frames"), 0644))
+
+	comment := func(s string) string {
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(s)))
+		return string(size) + s
+	}
+	comments := comment("vendor") + "\x02\x00\x00\x00" + comment("TRACKNUMBER=7") + comment("discnumber=1")
+	flac := filepath.Join(dir, "a.flac")
+	streamInfo := "\x00\x00\x00\x22" + strings.Repeat("\x00", 0x22)
+	vorbisComment := "\x84" + string([]byte{0, 0, byte(len(comments))}) + comments
+	assert.NoError(t, os.WriteFile(flac, []byte("fLaC"+streamInfo+vorbisComment+"audio frames"), 0644))
+
+	t.Run("id3", func(t *testing.T) {
+		disc, track, err := readTrackNumber(mp3)
+		assert.NoError(t, err)
+		assert.Equal(t, [2]int{2, 3}, [2]int{disc, track})
+	})
+	t.Run("vorbis comment", func(t *testing.T) {
+		disc, track, err := readTrackNumber(flac)
+		assert.NoError(t, err)
+		assert.Equal(t, [2]int{1, 7}, [2]int{disc, track})
+	})
+	t.Run("invalid flac", func(t *testing.T) {
+		_, err := parseFLAC(strings.NewReader("fLaX"), false)
+		assert.EqualError(t, err, ErrInvalidFLACIdentifier.Error())
+		_, err = parseFLAC(strings.NewReader("fLaC\x84\x00\x00\x10short"), false)
+		assert.EqualError(t, err, ErrInvalidFLACBlock.Error())
+	})
+}
@@ -0,0 +1,104 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"math"
+)
+
+// seekKeyStep is how many seconds the arrow keys seek
+const seekKeyStep = 5
+
+// seekSlider is the progress slider, OnChangeEnded is only fired once the drag is released so a drag seek once.
+// A tooltip show the time under the pointer while dragging
+type seekSlider struct {
+	widget.Slider
+	dragging    bool
+	tooltip     *widget.PopUp
+	tooltipText *widget.Label
+}
+
+func newSeekSlider() *seekSlider {
+	s := &seekSlider{}
+	s.Step = 0.01 // fine enough for the playback to move it smoothly
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *seekSlider) Dragged(e *fyne.DragEvent) {
+	s.Slider.Dragged(e)
+	if s.Disabled() {
+		return
+	}
+	s.dragging = true
+	s.showTooltip(e.Position.X)
+}
+
+func (s *seekSlider) DragEnd() {
+	s.dragging = false
+	if s.tooltip != nil {
+		s.tooltip.Hide()
+	}
+	s.Slider.DragEnd()
+}
+
+func (s *seekSlider) TypedKey(key *fyne.KeyEvent) {
+	if s.Disabled() {
+		return
+	}
+	switch key.Name {
+	case fyne.KeyLeft:
+		s.SetValue(s.Value - seekKeyStep)
+	case fyne.KeyRight:
+		s.SetValue(s.Value + seekKeyStep)
+	}
+}
+
+// showTooltip show the dragged time above the slider at x
+func (s *seekSlider) showTooltip(x float32) {
+	c := fyne.CurrentApp().Driver().CanvasForObject(s)
+	if c == nil {
+		return
+	}
+	if s.tooltip == nil {
+		s.tooltipText = widget.NewLabel("")
+		s.tooltip = widget.NewPopUp(s.tooltipText, c)
+	}
+	s.tooltipText.SetText(formatTime(s.Value))
+	size := s.tooltip.Content.MinSize()
+	position := fyne.CurrentApp().Driver().AbsolutePositionForObject(s)
+	x = min(max(x, 0), s.Size().Width)
+	s.tooltip.ShowAtPosition(fyne.NewPos(position.X+x-size.Width/2, position.Y-size.Height))
+}
+
+// tappableLabel is a label calling onTapped when clicked
+type tappableLabel struct {
+	widget.Label
+	onTapped func()
+}
+
+func newTappableLabel(text string, onTapped func()) *tappableLabel {
+	l := &tappableLabel{onTapped: onTapped}
+	l.Text = text
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+func (l *tappableLabel) Tapped(*fyne.PointEvent) {
+	if l.onTapped != nil {
+		l.onTapped()
+	}
+}
+
+func (l *tappableLabel) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
+
+// durationText is the duration, or the time left counted down as -mm:ss
+func durationText(position, duration float64, remaining bool) string {
+	if remaining {
+		return "-" + formatTime(math.Ceil(max(duration-position, 0)))
+	}
+	return formatTime(duration)
+}
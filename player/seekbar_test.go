@@ -0,0 +1,20 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDurationText(t *testing.T) {
+	t.Run("duration", func(t *testing.T) {
+		assert.Equal(t, "03:25", durationText(42.5, 205.7, false))
+	})
+	t.Run("remaining counted down to zero at the end", func(t *testing.T) {
+		assert.Equal(t, "-03:06", durationText(0.5, 186, true))
+		assert.Equal(t, "-00:01", durationText(185.2, 186, true))
+		assert.Equal(t, "-00:00", durationText(186, 186, true))
+	})
+	t.Run("position past the duration", func(t *testing.T) {
+		assert.Equal(t, "-00:00", durationText(190, 186, true))
+	})
+}
@@ -0,0 +1,68 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorgemul/musicplayer/engine"
+	"os"
+	"path/filepath"
+)
+
+type settings struct {
+	PlayPercent         int                         `json:"playPercent"`   // a song count as played once this percent of it is heard
+	PlaySeconds         int                         `json:"playSeconds"`   // or once this many seconds are heard, 0 to only use the percent
+	ShowRemaining       bool                        `json:"showRemaining"` // whether the time left is shown rather than the duration
+	ShowVisualizer      bool                        `json:"showVisualizer"`
+	Equalizer           engine.Equalizer            `json:"equalizer"`
+	EqualizerPresets    map[string]engine.Equalizer `json:"equalizerPresets"` // saved by the user, by name
+	ReplayGain          replayGainSettings          `json:"replayGain"`
+	WriteReplayGainTags bool                        `json:"writeReplayGainTags"` // whether the loudness measure write its result to the mp3 tags
+	Speed               speedSettings               `json:"speed"`
+}
+
+func defaultSettings() settings {
+	return settings{PlayPercent: 50, PlaySeconds: 240, ReplayGain: replayGainSettings{Mode: gainOff, PreventClipping: true}}
+}
+
+func settingsFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "settings.json"), nil
+}
+
+// loadSettings return the defaults for the settings never saved
+func loadSettings() (settings, error) {
+	loaded := defaultSettings()
+	file, err := settingsFile()
+	if err != nil {
+		return loaded, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return loaded, nil
+	}
+	if err != nil {
+		return loaded, err
+	}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return defaultSettings(), err
+	}
+	return loaded, nil
+}
+
+func saveSettings(s settings) error {
+	file, err := settingsFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
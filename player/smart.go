@@ -0,0 +1,207 @@
+package player
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSmartRule = errors.New("smart playlist: invalid rule")
+
+// smartRule compare a library field with the value, see smartFields for the operators of each field
+type smartRule struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type smartQuery struct {
+	MatchAny   bool        `json:"matchAny,omitempty"` // combine the rules with OR instead of AND
+	Rules      []smartRule `json:"rules"`
+	SortBy     string      `json:"sortBy,omitempty"`
+	Descending bool        `json:"descending,omitempty"`
+	Limit      int         `json:"limit,omitempty"` // 0 means no limit
+}
+
+type smartFieldKind int
+
+const (
+	textField smartFieldKind = iota
+	numberField
+	dateField
+)
+
+var (
+	smartFieldNames = []string{"title", "artist", "album", "genre", "year", "track", "duration", "added", "plays", "skips", "last played", "path"}
+	smartFields     = map[string]smartFieldKind{
+		"title":       textField,
+		"artist":      textField,
+		"album":       textField,
+		"genre":       textField,
+		"path":        textField,
+		"year":        numberField,
+		"track":       numberField,
+		"duration":    numberField, // seconds
+		"plays":       numberField,
+		"skips":       numberField,
+		"added":       dateField,
+		"last played": dateField, // never played songs are not in any last days
+	}
+	smartOperators = map[smartFieldKind][]string{
+		textField:   {"is", "is not", "contains", "does not contain"},
+		numberField: {"=", "!=", "<", ">"},
+		dateField:   {"in last days", "not in last days"},
+	}
+)
+
+func (t libraryTrack) text(field string) string {
+	switch field {
+	case "title":
+		return t.Title
+	case "artist":
+		return t.Artist
+	case "album":
+		return t.Album
+	case "genre":
+		return t.Genre
+	}
+	return t.Path
+}
+
+func (t libraryTrack) number(field string, stats trackStats) int {
+	switch field {
+	case "year":
+		return t.Year
+	case "track":
+		return t.Track
+	case "duration":
+		return int(t.Duration.Seconds())
+	case "skips":
+		return stats.skips
+	}
+	return stats.plays
+}
+
+func (t libraryTrack) date(field string, stats trackStats) time.Time {
+	if field == "last played" {
+		return stats.lastPlayed
+	}
+	return t.Added
+}
+
+func (r smartRule) validate() error {
+	kind, exist := smartFields[r.Field]
+	if !exist || !slices.Contains(smartOperators[kind], r.Operator) {
+		return fmt.Errorf("%w: %s %s", ErrInvalidSmartRule, r.Field, r.Operator)
+	}
+	if kind == textField {
+		return nil
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(r.Value)); err != nil {
+		return fmt.Errorf("%w: %s %s %q is not a number", ErrInvalidSmartRule, r.Field, r.Operator, r.Value)
+	}
+	return nil
+}
+
+// match assume the rule is valid, text is compared ignoring case and accents
+func (r smartRule) match(t libraryTrack, stats trackStats, now time.Time) bool {
+	switch smartFields[r.Field] {
+	case textField:
+		text, value := foldText(t.text(r.Field)), foldText(strings.TrimSpace(r.Value))
+		switch r.Operator {
+		case "is":
+			return text == value
+		case "is not":
+			return text != value
+		case "contains":
+			return strings.Contains(text, value)
+		}
+		return !strings.Contains(text, value)
+	case numberField:
+		number := t.number(r.Field, stats)
+		value, _ := strconv.Atoi(strings.TrimSpace(r.Value))
+		switch r.Operator {
+		case "=":
+			return number == value
+		case "!=":
+			return number != value
+		case "<":
+			return number < value
+		}
+		return number > value
+	}
+	days, _ := strconv.Atoi(strings.TrimSpace(r.Value))
+	inLast := t.date(r.Field, stats).After(now.AddDate(0, 0, -days))
+	return inLast == (r.Operator == "in last days")
+}
+
+func (q smartQuery) validate() error {
+	for _, rule := range q.Rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	if _, exist := smartFields[q.SortBy]; q.SortBy != "" && !exist {
+		return fmt.Errorf("%w: sort by %s", ErrInvalidSmartRule, q.SortBy)
+	}
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidSmartRule)
+	}
+	return nil
+}
+
+// evaluate select the library tracks matching the rules, without rules every track match
+func (q smartQuery) evaluate(tracks map[string]libraryTrack, stats map[historyKey]trackStats, now time.Time) []libraryTrack {
+	var matched []libraryTrack
+	for _, track := range tracks {
+		match := !q.MatchAny || len(q.Rules) == 0
+		for _, rule := range q.Rules {
+			if rule.match(track, stats[historyKey{path: track.Path}], now) == q.MatchAny {
+				match = q.MatchAny
+				break
+			}
+		}
+		if match {
+			matched = append(matched, track)
+		}
+	}
+	slices.SortFunc(matched, func(a, b libraryTrack) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	if q.SortBy != "" {
+		slices.SortStableFunc(matched, func(a, b libraryTrack) int {
+			var c int
+			aStats, bStats := stats[historyKey{path: a.Path}], stats[historyKey{path: b.Path}]
+			switch smartFields[q.SortBy] {
+			case textField:
+				c = naturalCompare(a.text(q.SortBy), b.text(q.SortBy))
+			case numberField:
+				c = cmp.Compare(a.number(q.SortBy, aStats), b.number(q.SortBy, bStats))
+			case dateField:
+				c = a.date(q.SortBy, aStats).Compare(b.date(q.SortBy, bStats))
+			}
+			if q.Descending {
+				return -c
+			}
+			return c
+		})
+	}
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+// librarySongs turn the library tracks into playlist songs
+func librarySongs(tracks []libraryTrack) []song {
+	var songs []song
+	for _, track := range tracks {
+		songs = append(songs, song{path: track.Path, name: filepath.Base(track.Path)})
+	}
+	return songs
+}
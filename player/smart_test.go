@@ -0,0 +1,71 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSmartPlaylist(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tracks := map[string]libraryTrack{
+		"/a.mp3": {Path: "/a.mp3", Title: "So What", Genre: "Jazz", Year: 1959, Added: now.AddDate(0, -6, 0)},
+		"/b.mp3": {Path: "/b.mp3", Title: "Naima", Genre: "jazz", Year: 1960, Added: now.AddDate(0, 0, -3)},
+		"/c.mp3": {Path: "/c.mp3", Title: "Teen Town", Genre: "Jazz Fusion", Year: 1977, Added: now.AddDate(0, 0, -10)},
+		"/d.mp3": {Path: "/d.mp3", Title: "Café", Genre: "Pop", Year: 2001, Added: now.AddDate(0, 0, -40)},
+	}
+	stats := map[historyKey]trackStats{
+		{path: "/a.mp3"}: {plays: 4, lastPlayed: now.AddDate(0, -1, 0)},
+		{path: "/c.mp3"}: {plays: 1, skips: 2, lastPlayed: now.AddDate(0, 0, -1)},
+		{path: "/d.mp3"}: {skips: 1},
+	}
+	paths := func(tracks []libraryTrack) []string {
+		var paths []string
+		for _, track := range tracks {
+			paths = append(paths, track.Path)
+		}
+		return paths
+	}
+
+	t.Run("match all", func(t *testing.T) {
+		query := smartQuery{Rules: []smartRule{{"genre", "is", "Jazz"}, {"year", "<", "1970"}}}
+		assert.NoError(t, query.validate())
+		assert.Equal(t, []string{"/a.mp3", "/b.mp3"}, paths(query.evaluate(tracks, stats, now)))
+	})
+	t.Run("recently added and never played", func(t *testing.T) {
+		query := smartQuery{Rules: []smartRule{{"added", "in last days", "30"}, {"plays", "=", "0"}}}
+		assert.Equal(t, []string{"/b.mp3"}, paths(query.evaluate(tracks, stats, now)))
+	})
+	t.Run("play statistics", func(t *testing.T) {
+		query := smartQuery{Rules: []smartRule{{"last played", "not in last days", "7"}, {"skips", ">", "0"}}}
+		assert.Equal(t, []string{"/d.mp3"}, paths(query.evaluate(tracks, stats, now)))
+		query = smartQuery{SortBy: "plays", Descending: true, Limit: 2}
+		assert.Equal(t, []string{"/a.mp3", "/c.mp3"}, paths(query.evaluate(tracks, stats, now)))
+	})
+	t.Run("match any", func(t *testing.T) {
+		query := smartQuery{MatchAny: true, Rules: []smartRule{{"title", "contains", "cafe"}, {"genre", "contains", "fusion"}}}
+		assert.Equal(t, []string{"/c.mp3", "/d.mp3"}, paths(query.evaluate(tracks, stats, now)))
+	})
+	t.Run("sort and limit", func(t *testing.T) {
+		query := smartQuery{SortBy: "year", Descending: true, Limit: 3}
+		assert.Equal(t, []string{"/d.mp3", "/c.mp3", "/b.mp3"}, paths(query.evaluate(tracks, stats, now)))
+		query = smartQuery{Rules: []smartRule{{"added", "not in last days", "7"}}, SortBy: "added"}
+		assert.Equal(t, []string{"/a.mp3", "/d.mp3", "/c.mp3"}, paths(query.evaluate(tracks, stats, now)))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, query := range []smartQuery{
+			{Rules: []smartRule{{"mood", "is", "happy"}}},
+			{Rules: []smartRule{{"year", "contains", "19"}}},
+			{Rules: []smartRule{{"year", "<", "old"}}},
+			{SortBy: "mood"},
+			{Limit: -1},
+		} {
+			assert.ErrorIs(t, query.validate(), ErrInvalidSmartRule)
+		}
+	})
+	t.Run("genre", func(t *testing.T) {
+		assert.Equal(t, "Jazz", parseGenre("(8)Jazz"))
+		assert.Equal(t, "(8)", parseGenre("(8)"))
+		assert.Equal(t, "Rock", parseGenre("Rock"))
+	})
+}
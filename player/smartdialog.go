@@ -0,0 +1,158 @@
+package player
+
+import (
+	"cmp"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setSmart fill the playlist from the library by the rules, the playing song keep its highlight when it still match
+func (p *Player) setSmart(pl *Playlist, query *smartQuery) {
+	pl.smart = query
+	songs := librarySongs(query.evaluate(p.library.tracks, p.stats, time.Now()))
+	playing := -1
+	if pl.playingIndex != -1 {
+		playing = slices.IndexFunc(songs, pl.songs[pl.playingIndex].is)
+	}
+	pl.songs = songs
+	pl.playingIndex = playing
+	pl.sorted = false
+	pl.UI.ImportFromFileBtn.Hide()
+	pl.UI.ImportFromDirBtn.Hide()
+	pl.UI.ImportPlaylistBtn.Hide()
+	pl.UI.RulesBtn.Show()
+	p.refreshPlaylist(pl)
+	if p.Playlist == pl && playing == -1 {
+		p.UI.PrevBtn.Disable()
+		p.UI.NextBtn.Disable()
+	} else if p.Playlist == pl {
+		p.updateSkipButtons()
+	}
+}
+
+// createSmartPlaylist ask the name and rules of a new smart playlist, which is saved right away
+func (p *Player) createSmartPlaylist() {
+	p.showSmartEditor("New smart playlist", "", true, smartQuery{}, func(name string, query smartQuery) {
+		if err := savePlaylist(name, nil, &query); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		p.refreshSavedPlaylists()
+		p.openPlaylist(name)
+	})
+}
+
+type smartRuleRow struct {
+	field    *widget.Select
+	operator *widget.Select
+	value    *widget.Entry
+}
+
+// showSmartEditor edit the rules of the query, an invalid query reopen the editor after showing the error
+func (p *Player) showSmartEditor(title, name string, askName bool, query smartQuery, onConfirm func(name string, query smartQuery)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(name)
+	nameEntry.Validator = validatePlaylistName
+	match := widget.NewSelect([]string{"all rules", "any rule"}, nil)
+	match.SetSelectedIndex(0)
+	if query.MatchAny {
+		match.SetSelectedIndex(1)
+	}
+	var (
+		rows    []*smartRuleRow
+		ruleBox = container.NewVBox()
+		addRule func(rule smartRule)
+	)
+	addRule = func(rule smartRule) {
+		row := &smartRuleRow{
+			operator: widget.NewSelect(nil, nil),
+			value:    widget.NewEntry(),
+		}
+		row.field = widget.NewSelect(smartFieldNames, func(field string) {
+			operators := smartOperators[smartFields[field]]
+			row.operator.SetOptions(operators)
+			if !slices.Contains(operators, row.operator.Selected) {
+				row.operator.SetSelectedIndex(0)
+			}
+		})
+		row.field.SetSelected(cmp.Or(rule.Field, smartFieldNames[0]))
+		if rule.Operator != "" {
+			row.operator.SetSelected(rule.Operator)
+		}
+		row.value.SetText(rule.Value)
+		var line *fyne.Container
+		removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+			rows = slices.DeleteFunc(rows, func(other *smartRuleRow) bool { return other == row })
+			ruleBox.Remove(line)
+		})
+		line = container.NewBorder(nil, nil, container.NewHBox(row.field, row.operator), removeBtn, row.value)
+		rows = append(rows, row)
+		ruleBox.Add(line)
+	}
+	for _, rule := range query.Rules {
+		addRule(rule)
+	}
+	addBtn := widget.NewButtonWithIcon("add rule", theme.ContentAddIcon(), func() {
+		addRule(smartRule{})
+	})
+	sortBy := widget.NewSelect(append([]string{"none"}, smartFieldNames...), nil)
+	sortBy.SetSelected(cmp.Or(query.SortBy, "none"))
+	descending := widget.NewCheck("descending", nil)
+	descending.SetChecked(query.Descending)
+	limit := widget.NewEntry()
+	limit.SetPlaceHolder("no limit")
+	if query.Limit > 0 {
+		limit.SetText(strconv.Itoa(query.Limit))
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Match", match),
+		widget.NewFormItem("Sort by", container.NewHBox(sortBy, descending)),
+		widget.NewFormItem("Limit", limit),
+	)
+	if askName {
+		form.Items = append([]*widget.FormItem{widget.NewFormItem("Name", nameEntry)}, form.Items...)
+	}
+	content := container.NewBorder(form, addBtn, nil, nil, container.NewVScroll(ruleBox))
+	editor := dialog.NewCustomConfirm(title, "Save", "Cancel", content, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		edited := smartQuery{MatchAny: match.SelectedIndex() == 1, Descending: descending.Checked}
+		for _, row := range rows {
+			edited.Rules = append(edited.Rules, smartRule{Field: row.field.Selected, Operator: row.operator.Selected, Value: row.value.Text})
+		}
+		if sortBy.Selected != "none" {
+			edited.SortBy = sortBy.Selected
+		}
+		err := edited.validate()
+		if text := strings.TrimSpace(limit.Text); text != "" && err == nil {
+			if edited.Limit, err = strconv.Atoi(text); err != nil || edited.Limit < 0 {
+				err = fmt.Errorf("%w: limit %q is not a positive number", ErrInvalidSmartRule, text)
+			}
+		}
+		if askName && err == nil {
+			err = validatePlaylistName(nameEntry.Text)
+		}
+		if err != nil {
+			errorDialog := dialog.NewError(err, p.window)
+			errorDialog.SetOnClosed(func() {
+				p.showSmartEditor(title, nameEntry.Text, askName, edited, onConfirm)
+			})
+			errorDialog.Show()
+			return
+		}
+		onConfirm(nameEntry.Text, edited)
+	}, p.window)
+	windowSize := p.window.Canvas().Size()
+	editor.Resize(fyne.NewSize(windowSize.Width*0.7, windowSize.Height*0.7))
+	editor.Show()
+}
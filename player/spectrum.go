@@ -0,0 +1,140 @@
+package player
+
+import (
+	"github.com/gopxl/beep"
+	"math"
+	"math/cmplx"
+	"slices"
+	"time"
+)
+
+const (
+	fftSize       = 2048
+	spectrumBands = 32
+	spectrumLow   = 30.0    // Hz, lowest frequency of the first bar
+	spectrumHigh  = 16000.0 // Hz, highest of the last bar
+	spectrumFloor = -72.0   // dB shown as an empty bar
+	meterFloor    = -60.0   // dB shown as an empty meter
+	fallSpeed     = 1.5     // part of the height a bar or a meter fall by second
+	peakHold      = time.Second
+	clipHold      = 2 * time.Second
+)
+
+// fft transform x in place, its length is a power of two
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				a, b := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// visualFrame is what the visualizer draw, heights from 0 to 1
+type visualFrame struct {
+	bars     []float64
+	levels   [2]float64
+	peaks    [2]float64 // held levels
+	clipping [2]bool
+}
+
+// analyzer turn the latest samples into a frame, the falling bars and the held peaks are kept between updates
+type analyzer struct {
+	frame    visualFrame
+	window   []float64 // hann window
+	gain     float64   // scale a bin magnitude to the amplitude of its sine
+	buf      []complex128
+	bands    []int // first bin of every band, then the end of the last
+	peakAge  [2]time.Duration
+	clipLeft [2]time.Duration // how long the clip indicator stay lit
+}
+
+func newAnalyzer(rate beep.SampleRate) *analyzer {
+	a := &analyzer{
+		frame:  visualFrame{bars: make([]float64, spectrumBands)},
+		window: make([]float64, fftSize),
+		buf:    make([]complex128, fftSize),
+	}
+	var sum float64
+	for i := range a.window {
+		a.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fftSize-1))
+		sum += a.window[i]
+	}
+	a.gain = 2 / sum
+	bin := func(band int) int {
+		frequency := spectrumLow * math.Pow(spectrumHigh/spectrumLow, float64(band)/spectrumBands)
+		return min(int(math.Round(frequency*fftSize/float64(rate))), fftSize/2)
+	}
+	for band := range spectrumBands + 1 {
+		a.bands = append(a.bands, bin(band))
+	}
+	return a
+}
+
+// update analyze the latest fftSize samples, elapsed is the time since the previous update
+func (a *analyzer) update(samples [][2]float64, elapsed time.Duration) {
+	fall := fallSpeed * elapsed.Seconds()
+	var peak [2]float64
+	for i, sample := range samples[:fftSize] {
+		a.buf[i] = complex((sample[0]+sample[1])/2*a.window[i], 0)
+		peak[0] = max(peak[0], math.Abs(sample[0]))
+		peak[1] = max(peak[1], math.Abs(sample[1]))
+	}
+	fft(a.buf)
+	for band := range spectrumBands {
+		from := a.bands[band]
+		to := max(a.bands[band+1], from+1) // a low band narrower than a bin still show its nearest bin
+		var magnitude float64
+		for _, x := range a.buf[from:min(to, fftSize/2+1)] {
+			magnitude = max(magnitude, cmplx.Abs(x)*a.gain)
+		}
+		a.frame.bars[band] = max(height(magnitude, spectrumFloor), a.frame.bars[band]-fall)
+	}
+	for c := range 2 {
+		level := height(peak[c], meterFloor)
+		a.frame.levels[c] = max(level, a.frame.levels[c]-fall)
+		a.peakAge[c] += elapsed
+		if level >= a.frame.peaks[c] {
+			a.frame.peaks[c], a.peakAge[c] = level, 0
+		} else if a.peakAge[c] > peakHold {
+			a.frame.peaks[c] = max(a.frame.peaks[c]-fall, a.frame.levels[c])
+		}
+		if peak[c] >= 1 {
+			a.clipLeft[c] = clipHold
+		} else {
+			a.clipLeft[c] = max(a.clipLeft[c]-elapsed, 0)
+		}
+		a.frame.clipping[c] = a.clipLeft[c] > 0
+	}
+}
+
+// snapshot copy the frame, so it can be drawn while the analyzer update
+func (a *analyzer) snapshot() visualFrame {
+	frame := a.frame
+	frame.bars = slices.Clone(a.frame.bars)
+	return frame
+}
+
+// height of the amplitude on a scale from floor dB to 0 dB
+func height(amplitude, floor float64) float64 {
+	if amplitude <= 0 {
+		return 0
+	}
+	return min(max((20*math.Log10(amplitude)-floor)/-floor, 0), 1)
+}
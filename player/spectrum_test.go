@@ -0,0 +1,87 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// sine of the frequency at 44100 Hz, the right channel scaled by right
+func sine(frequency, amplitude, right float64) [][2]float64 {
+	samples := make([][2]float64, fftSize)
+	for i := range samples {
+		value := amplitude * math.Sin(2*math.Pi*frequency*float64(i)/44100)
+		samples[i] = [2]float64{value, value * right}
+	}
+	return samples
+}
+
+func TestSpectrum(t *testing.T) {
+	t.Run("fft match the dft", func(t *testing.T) {
+		x := make([]complex128, 16)
+		for i := range x {
+			x[i] = complex(math.Sin(float64(i)*0.7)+float64(i%3), 0)
+		}
+		want := make([]complex128, len(x))
+		for k := range want {
+			for n, value := range x {
+				want[k] += value * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(x))))
+			}
+		}
+		fft(x)
+		for k := range x {
+			assert.InDelta(t, real(want[k]), real(x[k]), 1e-9)
+			assert.InDelta(t, imag(want[k]), imag(x[k]), 1e-9)
+		}
+	})
+	t.Run("the bar of the tone is the highest", func(t *testing.T) {
+		a := newAnalyzer(44100)
+		a.update(sine(1000, 0.5, 1), visualizerInterval)
+		highest := 0
+		for band, bar := range a.frame.bars {
+			if bar > a.frame.bars[highest] {
+				highest = band
+			}
+		}
+		low := spectrumLow * math.Pow(spectrumHigh/spectrumLow, float64(highest)/spectrumBands)
+		high := spectrumLow * math.Pow(spectrumHigh/spectrumLow, float64(highest+1)/spectrumBands)
+		assert.True(t, low <= 1050 && high >= 950, "band %d from %.0f to %.0f Hz", highest, low, high)
+		assert.InDelta(t, height(0.5, spectrumFloor), a.frame.bars[highest], 0.03, "-6 dB")
+		assert.Less(t, a.frame.bars[0], 0.3)
+	})
+	t.Run("meters", func(t *testing.T) {
+		a := newAnalyzer(44100)
+		a.update(sine(440, 0.5, 0.1), visualizerInterval)
+		assert.InDelta(t, height(0.5, meterFloor), a.frame.levels[0], 0.01)
+		assert.InDelta(t, height(0.05, meterFloor), a.frame.levels[1], 0.01)
+		assert.Equal(t, [2]bool{}, a.frame.clipping)
+	})
+	t.Run("peaks are held then fall", func(t *testing.T) {
+		a := newAnalyzer(44100)
+		a.update(sine(440, 0.5, 1), visualizerInterval)
+		held := a.frame.peaks[0]
+		silence := make([][2]float64, fftSize)
+		a.update(silence, peakHold/2)
+		assert.Less(t, a.frame.levels[0], held, "the level fall")
+		assert.Equal(t, held, a.frame.peaks[0], "the peak is held")
+		a.update(silence, peakHold)
+		assert.Less(t, a.frame.peaks[0], held)
+	})
+	t.Run("clipping stay lit", func(t *testing.T) {
+		a := newAnalyzer(44100)
+		a.update(sine(440, 1.2, 0.5), visualizerInterval)
+		assert.Equal(t, [2]bool{true, false}, a.frame.clipping)
+		a.update(make([][2]float64, fftSize), clipHold/2)
+		assert.True(t, a.frame.clipping[0])
+		a.update(make([][2]float64, fftSize), clipHold)
+		assert.False(t, a.frame.clipping[0])
+	})
+}
+
+func TestHeight(t *testing.T) {
+	assert.Equal(t, 0.0, height(0, meterFloor))
+	assert.Equal(t, 1.0, height(1, meterFloor))
+	assert.Equal(t, 1.0, height(2, meterFloor), "clipped")
+	assert.InDelta(t, 0.5, height(math.Pow(10, -30.0/20), meterFloor), 1e-9)
+}
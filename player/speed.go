@@ -0,0 +1,65 @@
+package player
+
+import (
+	"github.com/gorgemul/musicplayer/engine"
+	"strconv"
+	"time"
+)
+
+// speedSettings remember the playback speed by track and by playlist, the speed of a track win over the one of its playlist
+type speedSettings struct {
+	Mode      engine.SpeedMode   `json:"mode"`
+	Tracks    map[string]float64 `json:"tracks"`    // by speedKey
+	Playlists map[string]float64 `json:"playlists"` // by playlist name
+}
+
+// speedKey tell the virtual tracks of a cue sheet apart by their start
+func speedKey(s song) string {
+	if s.start > 0 {
+		return s.path + "#" + strconv.FormatInt(int64(s.start/time.Millisecond), 10)
+	}
+	return s.path
+}
+
+// speed of the track of the playlist, 1 when none is remembered
+func (s speedSettings) speed(key, playlist string) float64 {
+	if speed, ok := s.Tracks[key]; ok {
+		return speed
+	}
+	if speed, ok := s.Playlists[playlist]; ok {
+		return speed
+	}
+	return 1
+}
+
+// remember the speed for the track, or for the playlist which the track then follow. A speed the track would get anyway is forgotten
+func (s *speedSettings) remember(speed float64, key, playlist string, byTrack bool) {
+	if s.Tracks == nil {
+		s.Tracks = map[string]float64{}
+	}
+	if s.Playlists == nil {
+		s.Playlists = map[string]float64{}
+	}
+	if !byTrack {
+		delete(s.Tracks, key)
+		if s.Playlists[playlist] = speed; speed == 1 {
+			delete(s.Playlists, playlist)
+		}
+		return
+	}
+	if s.Tracks[key] = speed; s.speedOfPlaylist(playlist) == speed {
+		delete(s.Tracks, key)
+	}
+}
+
+func (s speedSettings) speedOfPlaylist(playlist string) float64 {
+	if speed, ok := s.Playlists[playlist]; ok {
+		return speed
+	}
+	return 1
+}
+
+// speedText write the speed as 1.25×
+func speedText(speed float64) string {
+	return strconv.FormatFloat(speed, 'f', -1, 64) + "×"
+}
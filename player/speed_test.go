@@ -0,0 +1,44 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSpeed(t *testing.T) {
+	t.Run("key", func(t *testing.T) {
+		assert.Equal(t, "a.mp3", speedKey(song{path: "a.mp3"}))
+		assert.Equal(t, "a.flac#90500", speedKey(song{path: "a.flac", start: 90500 * time.Millisecond, end: time.Hour}))
+	})
+	t.Run("track win over playlist", func(t *testing.T) {
+		var s speedSettings
+		assert.Equal(t, 1.0, s.speed("a", "Podcasts"))
+		s.remember(1.5, "a", "Podcasts", false)
+		assert.Equal(t, 1.5, s.speed("a", "Podcasts"))
+		assert.Equal(t, 1.5, s.speed("b", "Podcasts"))
+		assert.Equal(t, 1.0, s.speed("a", "Music"))
+		s.remember(2, "b", "Podcasts", true)
+		assert.Equal(t, 2.0, s.speed("b", "Podcasts"))
+		assert.Equal(t, 2.0, s.speed("b", "Music"), "the track speed follow it")
+		s.remember(1.25, "b", "Podcasts", false)
+		assert.Equal(t, 1.25, s.speed("b", "Podcasts"), "the track now follow the playlist")
+		assert.Equal(t, 1.25, s.speed("a", "Podcasts"))
+	})
+	t.Run("default speed is forgotten", func(t *testing.T) {
+		var s speedSettings
+		s.remember(2, "a", "Podcasts", true)
+		s.remember(1, "a", "Podcasts", true)
+		assert.Empty(t, s.Tracks)
+		s.remember(0.75, "a", "Podcasts", false)
+		s.remember(1, "a", "Podcasts", false)
+		assert.Empty(t, s.Playlists)
+		s.remember(1.5, "a", "Podcasts", false)
+		s.remember(1, "a", "Podcasts", true)
+		assert.Equal(t, 1.0, s.speed("a", "Podcasts"), "the track is kept at normal speed in a faster playlist")
+	})
+	t.Run("text", func(t *testing.T) {
+		assert.Equal(t, "1×", speedText(1))
+		assert.Equal(t, "1.25×", speedText(1.25))
+	})
+}
@@ -0,0 +1,90 @@
+package player
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/gorgemul/musicplayer/engine"
+	"log"
+	"math"
+)
+
+const (
+	keepPitch        = "Keep pitch"
+	changePitch      = "Change pitch"
+	rememberTrack    = "This track"
+	rememberPlaylist = "This playlist"
+)
+
+// showSpeed open the speed control, a change is heard right away and remembered once the slider is released
+func (p *Player) showSpeed() {
+	key, playlist := speedKey(p.current.song), p.Playlist.name
+	speed, _ := p.engine.Speed()
+	label := widget.NewLabel(speedText(speed))
+	slider := widget.NewSlider(engine.MinSpeed, engine.MaxSpeed)
+	slider.Step = 0.05
+	slider.Value = speed
+	mode := widget.NewRadioGroup([]string{keepPitch, changePitch}, nil)
+	mode.Horizontal = true
+	mode.Required = true
+	mode.Selected = keepPitch
+	if p.settings.Speed.Mode == engine.ChangePitch {
+		mode.Selected = changePitch
+	}
+	remember := widget.NewRadioGroup([]string{rememberTrack, rememberPlaylist}, nil)
+	remember.Horizontal = true
+	remember.Required = true
+	remember.Selected = rememberTrack
+	if _, byTrack := p.settings.Speed.Tracks[key]; !byTrack && p.settings.Speed.speedOfPlaylist(playlist) != 1 {
+		remember.Selected = rememberPlaylist
+	}
+	value := func() float64 { return math.Round(slider.Value*100) / 100 } // the steps add up rounding errors
+	save := func() {
+		p.settings.Speed.remember(value(), key, playlist, remember.Selected == rememberTrack)
+		if err := saveSettings(p.settings); err != nil {
+			log.Println("saveSettings", err)
+		}
+	}
+	apply := func() {
+		p.engine.SetSpeed(value(), p.settings.Speed.Mode)
+		label.SetText(speedText(value()))
+		p.UI.SpeedBtn.SetText(speedText(value()))
+	}
+	slider.OnChanged = func(float64) { apply() }
+	slider.OnChangeEnded = func(float64) { save() }
+	mode.OnChanged = func(selected string) {
+		p.settings.Speed.Mode = engine.KeepPitch
+		if selected == changePitch {
+			p.settings.Speed.Mode = engine.ChangePitch
+		}
+		apply()
+		save()
+	}
+	remember.OnChanged = func(string) { save() }
+	presets := container.NewHBox()
+	for _, preset := range []float64{0.75, 1, 1.25, 1.5, 2} {
+		presets.Add(widget.NewButton(speedText(preset), func() { slider.SetValue(preset) }))
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("Speed", container.NewBorder(nil, presets, nil, label, slider)),
+		widget.NewFormItem("", mode),
+		widget.NewFormItem("Remember for", remember),
+	)
+	title := "Playback speed"
+	if p.current.song.path != "" {
+		title = fmt.Sprintf("Playback speed of %s", p.current.song.name)
+	}
+	speedDialog := dialog.NewCustom(title, "Close", form, p.window)
+	windowSize := p.window.Canvas().Size()
+	speedDialog.Resize(fyne.NewSize(windowSize.Width*0.5, 0))
+	speedDialog.Show()
+}
+
+// applySpeed play the current song at the speed remembered for it or its playlist
+func (p *Player) applySpeed() {
+	speed := p.settings.Speed.speed(speedKey(p.current.song), p.Playlist.name)
+	p.engine.SetSpeed(speed, p.settings.Speed.Mode)
+	p.UI.SpeedBtn.SetText(speedText(speed))
+}
@@ -0,0 +1,142 @@
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPlaylistName = errors.New("playlist name: should not be empty or contain path separator")
+
+type storedSong struct {
+	Path       string        `json:"path"`
+	Name       string        `json:"name"`
+	Title      string        `json:"title,omitempty"`
+	Artist     string        `json:"artist,omitempty"`
+	Album      string        `json:"album,omitempty"`
+	Track      int           `json:"track,omitempty"`
+	Duration   time.Duration `json:"duration,omitempty"`
+	Extensions []string      `json:"extensions,omitempty"`
+	Start      time.Duration `json:"start,omitempty"`
+	End        time.Duration `json:"end,omitempty"`
+}
+
+type storedPlaylist struct {
+	Name  string       `json:"name"`
+	Songs []storedSong `json:"songs"`
+	Smart *smartQuery  `json:"smart,omitempty"` // songs are re-evaluated from the library when set
+}
+
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "musicplayer"), nil
+}
+
+func playlistDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "playlists")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func validatePlaylistName(name string) error {
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return ErrInvalidPlaylistName
+	}
+	return nil
+}
+
+func savePlaylist(name string, songs []song, smart *smartQuery) error {
+	if err := validatePlaylistName(name); err != nil {
+		return err
+	}
+	dir, err := playlistDir()
+	if err != nil {
+		return err
+	}
+	stored := storedPlaylist{Name: name, Songs: []storedSong{}, Smart: smart}
+	for _, s := range songs {
+		stored.Songs = append(stored.Songs, storedSong{
+			Path:       s.path,
+			Name:       s.name,
+			Title:      s.title,
+			Artist:     s.artist,
+			Album:      s.album,
+			Track:      s.track,
+			Duration:   s.duration,
+			Extensions: s.extensions,
+			Start:      s.start,
+			End:        s.end,
+		})
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".json"), data, 0644)
+}
+
+func loadPlaylist(name string) ([]song, *smartQuery, error) {
+	if err := validatePlaylistName(name); err != nil {
+		return nil, nil, err
+	}
+	dir, err := playlistDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var stored storedPlaylist
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, nil, err
+	}
+	var songs []song
+	for _, s := range stored.Songs {
+		songs = append(songs, song{
+			path:       s.Path,
+			name:       s.Name,
+			title:      s.Title,
+			artist:     s.Artist,
+			album:      s.Album,
+			track:      s.Track,
+			duration:   s.Duration,
+			extensions: s.Extensions,
+			start:      s.Start,
+			end:        s.End,
+		})
+	}
+	return songs, stored.Smart, nil
+}
+
+func savedPlaylists() ([]string, error) {
+	dir, err := playlistDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
+			names = append(names, strings.TrimSuffix(file.Name(), ".json"))
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
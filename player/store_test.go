@@ -0,0 +1,45 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPlaylistStore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	t.Run("save and load", func(t *testing.T) {
+		songs := []song{
+			{path: "/music/a.mp3", name: "a.mp3"},
+			{path: "/music/b.wav", name: "Artist - B", title: "B", artist: "Artist", album: "Album", duration: 90 * time.Second, extensions: []string{`<meta rel="x">y</meta>`}},
+		}
+		assert.NoError(t, savePlaylist("road trip", songs, nil))
+		got, smart, err := loadPlaylist("road trip")
+		assert.NoError(t, err)
+		assert.Equal(t, songs, got)
+		assert.Nil(t, smart)
+	})
+	t.Run("save and load smart", func(t *testing.T) {
+		query := &smartQuery{Rules: []smartRule{{"genre", "is", "Jazz"}}, SortBy: "year", Limit: 10}
+		assert.NoError(t, savePlaylist("jazz", nil, query))
+		_, smart, err := loadPlaylist("jazz")
+		assert.NoError(t, err)
+		assert.Equal(t, query, smart)
+	})
+	t.Run("list saved playlists", func(t *testing.T) {
+		assert.NoError(t, savePlaylist("chill", nil, nil))
+		names, err := savedPlaylists()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"chill", "jazz", "road trip"}, names)
+	})
+	t.Run("invalid name", func(t *testing.T) {
+		for _, name := range []string{"", "  ", "a/b", `a\b`, ".."} {
+			assert.EqualError(t, savePlaylist(name, nil, nil), ErrInvalidPlaylistName.Error())
+		}
+	})
+	t.Run("load not exist", func(t *testing.T) {
+		_, _, err := loadPlaylist("not exist")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,99 @@
+package player
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/gorgemul/musicplayer/static"
+	"golang.org/x/image/draw"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const thumbnailSize = 128
+
+var defaultCover = sync.OnceValue(func() image.Image {
+	cover, _, err := image.Decode(bytes.NewReader(static.DefaultCoverBytes))
+	if err != nil {
+		log.Fatal("default image should be correctly open:", err)
+	}
+	return cover
+})
+
+// thumbnail scale the image down to fit in size x size, keeping its aspect ratio
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(size*bounds.Dy()/bounds.Dx(), 1)
+	} else {
+		width = max(size*bounds.Dx()/bounds.Dy(), 1)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// readThumbnail scale the cover of the audio down, an unreadable or broken cover is shown as the default one
+func readThumbnail(path string) image.Image {
+	album, err := readAlbum(path)
+	if err != nil || album.Cover == nil {
+		return thumbnail(defaultCover(), thumbnailSize)
+	}
+	return thumbnail(album.Cover, thumbnailSize)
+}
+
+func thumbnailDir() (string, error) {
+	return cacheDir("thumbnails")
+}
+
+// cacheDir is the directory of the disk cache called name, created when missing
+func cacheDir(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "musicplayer", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cacheKey change with the audio, so a retagged or replaced file is not served from the stale cache
+func cacheKey(path string, info os.FileInfo) string {
+	sum := sha1.Sum(fmt.Appendf(nil, "%s\x00%d\x00%d", path, info.ModTime().UnixNano(), info.Size()))
+	return hex.EncodeToString(sum[:])
+}
+
+// readCachedThumbnail read the cover thumbnail from the disk cache, the audio is only parsed when it is not cached yet
+func readCachedThumbnail(path string) image.Image {
+	info, err := os.Stat(path)
+	if err != nil {
+		return thumbnail(defaultCover(), thumbnailSize)
+	}
+	dir, err := thumbnailDir()
+	if err != nil {
+		return readThumbnail(path)
+	}
+	cached := filepath.Join(dir, cacheKey(path, info)+".png")
+	if f, err := os.Open(cached); err == nil {
+		defer f.Close()
+		if img, err := png.Decode(f); err == nil {
+			return img
+		}
+	}
+	img := readThumbnail(path)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err == nil {
+		if err := os.WriteFile(cached, buf.Bytes(), 0644); err != nil {
+			log.Println("write thumbnail", err)
+		}
+	}
+	return img
+}
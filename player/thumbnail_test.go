@@ -0,0 +1,51 @@
+package player
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThumbnailCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	audio := filepath.Join(t.TempDir(), "a.wav")
+	assert.NoError(t, os.WriteFile(audio, []byte("no cover"), 0644))
+	dir, err := thumbnailDir()
+	assert.NoError(t, err)
+
+	img := readCachedThumbnail(audio)
+	assert.Equal(t, thumbnailSize, max(img.Bounds().Dx(), img.Bounds().Dy()))
+	cached, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, cached, 1)
+
+	readCachedThumbnail(audio)
+	cached, _ = os.ReadDir(dir)
+	assert.Len(t, cached, 1, "unchanged audio reuse its thumbnail")
+
+	assert.NoError(t, os.Chtimes(audio, time.Now(), time.Now().Add(time.Hour)))
+	readCachedThumbnail(audio)
+	cached, _ = os.ReadDir(dir)
+	assert.Len(t, cached, 2, "changed audio get a new thumbnail")
+}
+
+func TestBrokenCover(t *testing.T) {
+	frame := func(id, content string) []byte {
+		return append(binary.BigEndian.AppendUint32([]byte(id), uint32(len(content))), append([]byte{0, 0}, content...)...)
+	}
+	frames := append(frame("TIT2", "\x00Title\x00"), frame("APIC", "\x00image/png\x00\x03\x00not a png")...)
+	tag := append(append([]byte("ID3\x03\x00\x00"), synchsafeBytes(uint32(len(frames)))...), frames...)
+	audio := filepath.Join(t.TempDir(), "a.mp3")
+	assert.NoError(t, os.WriteFile(audio, tag, 0644))
+	_, err := extractCover([]byte("\x00image/png\x00\x03\x00not a png"))
+	assert.ErrorIs(t, err, ErrInvalidCover)
+	album, err := readAlbum(audio)
+	assert.NoError(t, err)
+	assert.Equal(t, "Title", album.Title, "the other frames are kept")
+	assert.Equal(t, defaultCover().Bounds(), album.Cover.Bounds())
+	img := readThumbnail(audio)
+	assert.Equal(t, thumbnail(defaultCover(), thumbnailSize).Bounds(), img.Bounds(), "the default cover")
+}
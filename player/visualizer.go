@@ -0,0 +1,158 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/gorgemul/musicplayer/engine"
+	"slices"
+	"time"
+)
+
+// visualizerInterval is how often the visualizer is drawn while shown
+const visualizerInterval = time.Second / 30
+
+// visualizer show the spectrum and the stereo meters of the tapped samples.
+// They are analyzed by its own goroutine while it is shown, never by the audio
+type visualizer struct {
+	widget.BaseWidget
+	tap   *engine.Tap
+	frame visualFrame
+	stop  chan struct{} // stop analyzing, nil while hidden
+}
+
+func newVisualizer(tap *engine.Tap) *visualizer {
+	v := &visualizer{tap: tap, frame: visualFrame{bars: make([]float64, spectrumBands)}}
+	v.Hidden = true
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+func (v *visualizer) Show() {
+	if v.stop == nil {
+		v.stop = make(chan struct{})
+		go v.analyze(v.stop)
+	}
+	v.BaseWidget.Show()
+}
+
+func (v *visualizer) Hide() {
+	if v.stop != nil {
+		close(v.stop)
+		v.stop = nil
+	}
+	v.BaseWidget.Hide()
+}
+
+// analyze the tap until stop is closed, the UI is only refreshed when the frame changed
+func (v *visualizer) analyze(stop chan struct{}) {
+	ticker := time.NewTicker(visualizerInterval)
+	defer ticker.Stop()
+	a := newAnalyzer(v.tap.SampleRate())
+	samples := make([][2]float64, fftSize)
+	var previous visualFrame
+	last := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			v.tap.Latest(samples)
+			a.update(samples, now.Sub(last))
+			last = now
+			frame := a.snapshot()
+			if slices.Equal(frame.bars, previous.bars) && frame.levels == previous.levels && frame.peaks == previous.peaks && frame.clipping == previous.clipping {
+				continue
+			}
+			previous = frame
+			fyne.Do(func() {
+				v.frame = frame
+				v.Refresh()
+			})
+		}
+	}
+}
+
+func (v *visualizer) CreateRenderer() fyne.WidgetRenderer {
+	r := &visualizerRenderer{visualizer: v}
+	for range spectrumBands {
+		bar := canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+		r.bars = append(r.bars, bar)
+		r.objects = append(r.objects, bar)
+	}
+	for c := range 2 {
+		r.meters[c] = canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+		r.levels[c] = canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+		r.peaks[c] = canvas.NewRectangle(theme.Color(theme.ColorNameForeground))
+		r.clips[c] = canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+		r.objects = append(r.objects, r.meters[c], r.levels[c], r.peaks[c], r.clips[c])
+	}
+	return r
+}
+
+type visualizerRenderer struct {
+	visualizer *visualizer
+	bars       []*canvas.Rectangle
+	meters     [2]*canvas.Rectangle // background of the meters
+	levels     [2]*canvas.Rectangle
+	peaks      [2]*canvas.Rectangle
+	clips      [2]*canvas.Rectangle // lit while the channel clip
+	objects    []fyne.CanvasObject
+}
+
+// Layout put the spectrum on the left and the left and right meters on the right, the clip indicators above them
+func (r *visualizerRenderer) Layout(size fyne.Size) {
+	frame := r.visualizer.frame
+	const meterWidth, clipHeight, peakHeight = 12, 8, 2
+	padding := theme.Padding()
+	spectrumWidth := size.Width - 2*(meterWidth+padding)
+	slot := spectrumWidth / spectrumBands
+	for i, bar := range r.bars {
+		height := size.Height * float32(frame.bars[i])
+		bar.Move(fyne.NewPos(slot*float32(i)+slot*0.1, size.Height-height))
+		bar.Resize(fyne.NewSize(slot*0.8, height))
+	}
+	meterHeight := size.Height - clipHeight - padding
+	for c := range 2 {
+		x := spectrumWidth + padding + float32(c)*(meterWidth+padding)
+		r.clips[c].Move(fyne.NewPos(x, 0))
+		r.clips[c].Resize(fyne.NewSize(meterWidth, clipHeight))
+		r.meters[c].Move(fyne.NewPos(x, clipHeight+padding))
+		r.meters[c].Resize(fyne.NewSize(meterWidth, meterHeight))
+		level := meterHeight * float32(frame.levels[c])
+		r.levels[c].Move(fyne.NewPos(x, size.Height-level))
+		r.levels[c].Resize(fyne.NewSize(meterWidth, level))
+		peak := meterHeight * float32(frame.peaks[c])
+		r.peaks[c].Move(fyne.NewPos(x, size.Height-max(peak, peakHeight)))
+		r.peaks[c].Resize(fyne.NewSize(meterWidth, peakHeight))
+		r.peaks[c].Hidden = frame.peaks[c] == 0
+	}
+}
+
+func (r *visualizerRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(300, 80)
+}
+
+func (r *visualizerRenderer) Refresh() {
+	for _, bar := range r.bars {
+		bar.FillColor = theme.Color(theme.ColorNamePrimary)
+	}
+	for c := range 2 {
+		r.meters[c].FillColor = theme.Color(theme.ColorNameInputBackground)
+		r.levels[c].FillColor = theme.Color(theme.ColorNamePrimary)
+		r.peaks[c].FillColor = theme.Color(theme.ColorNameForeground)
+		r.clips[c].FillColor = theme.Color(theme.ColorNameInputBackground)
+		if r.visualizer.frame.clipping[c] {
+			r.clips[c].FillColor = theme.Color(theme.ColorNameError)
+		}
+	}
+	r.Layout(r.visualizer.Size())
+	canvas.Refresh(r.visualizer)
+}
+
+func (r *visualizerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *visualizerRenderer) Destroy() {}
@@ -0,0 +1,186 @@
+package player
+
+import (
+	"context"
+	"fyne.io/fyne/v2"
+	"github.com/fsnotify/fsnotify"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const watchDebounce = time.Second
+
+// watchFolders call onChange with the changed paths once the folders are quiet for debounce, until ctx is done.
+// fsnotify only watch a single directory, so every sub directory is watched as well, including the ones created later
+func watchFolders(ctx context.Context, folders []string, debounce time.Duration, onChange func(paths []string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	addTree := func(root string) {
+		filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && entry.IsDir() {
+				if err := watcher.Add(path); err != nil {
+					log.Println("watcher.Add", err)
+				}
+			}
+			return nil
+		})
+	}
+	for _, folder := range folders {
+		addTree(folder)
+	}
+	go func() {
+		defer watcher.Close()
+		var (
+			changed = map[string]bool{}
+			timer   = time.NewTimer(debounce)
+		)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						addTree(event.Name)
+					}
+				}
+				changed[event.Name] = true
+				timer.Reset(debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("watcher", err)
+			case <-timer.C:
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				changed = map[string]bool{}
+				onChange(paths)
+			}
+		}
+	}()
+	return nil
+}
+
+// detectMoves pair the tracks gone from old with the new tracks of same size, mtime and tags, which rename and move keep.
+// A gone track with more than one candidate is ambiguous, so it is not paired
+func detectMoves(old, tracks map[string]libraryTrack) map[string]string {
+	moves := map[string]string{}
+	for path, track := range old {
+		if _, exist := tracks[path]; exist {
+			continue
+		}
+		var candidates []string
+		for newPath, newTrack := range tracks {
+			if _, existed := old[newPath]; existed {
+				continue
+			}
+			if newTrack.Size == track.Size && newTrack.ModTime.Equal(track.ModTime) && newTrack.metadata() == track.metadata() {
+				candidates = append(candidates, newPath)
+			}
+		}
+		if len(candidates) == 1 {
+			moves[path] = candidates[0]
+		}
+	}
+	return moves
+}
+
+// carryMoves keep when the moved tracks were added, otherwise they look newly added, and their measured loudness
+func carryMoves(moves map[string]string, old, tracks map[string]libraryTrack) {
+	for from, to := range moves {
+		track := tracks[to]
+		track.Added = old[from].Added
+		track.Loudness, track.ReplayGain = old[from].Loudness, old[from].ReplayGain
+		tracks[to] = track
+	}
+}
+
+// relocateSongs point the songs at the moved files, report whether any song is changed
+func relocateSongs(songs []song, moves map[string]string) bool {
+	changed := false
+	for i, s := range songs {
+		newPath, moved := moves[s.path]
+		if !moved {
+			continue
+		}
+		if s.name == filepath.Base(s.path) {
+			songs[i].name = filepath.Base(newPath)
+		}
+		songs[i].path = newPath
+		changed = true
+	}
+	return changed
+}
+
+// relocatePlaylists update the opened and saved playlists pointing at the moved files
+func (p *Player) relocatePlaylists(moves map[string]string) {
+	if len(moves) == 0 {
+		return
+	}
+	for _, pl := range p.playlists {
+		if pl.smart != nil || !relocateSongs(pl.songs, moves) {
+			continue
+		}
+		p.refreshPlaylist(pl)
+		if pl.name != "" {
+			if err := savePlaylist(pl.name, pl.songs, nil); err != nil {
+				log.Println("savePlaylist", err)
+			}
+		}
+	}
+	for _, name := range p.saved {
+		if slices.ContainsFunc(p.playlists, func(pl *Playlist) bool { return pl.name == name }) {
+			continue
+		}
+		songs, smart, err := loadPlaylist(name)
+		if err != nil || smart != nil || !relocateSongs(songs, moves) {
+			continue
+		}
+		if err := savePlaylist(name, songs, nil); err != nil {
+			log.Println("savePlaylist", err)
+		}
+	}
+}
+
+// watchLibrary restart watching the library folders, a change rescan the library
+func (p *Player) watchLibrary() {
+	if p.library.stopWatch != nil {
+		p.library.stopWatch()
+		p.library.stopWatch = nil
+	}
+	if len(p.library.folders) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	err := watchFolders(ctx, p.library.folders, watchDebounce, func(paths []string) {
+		fyne.Do(func() {
+			for _, path := range paths {
+				p.covers.forget(path)
+				p.metadata.forget(path)
+			}
+			p.rescanLibrary()
+		})
+	})
+	if err != nil {
+		cancel()
+		log.Println("watchFolders", err)
+		return
+	}
+	p.library.stopWatch = cancel
+}
@@ -0,0 +1,74 @@
+package player
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Run("debounced changes", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		changes := make(chan []string, 10)
+		assert.NoError(t, watchFolders(ctx, []string{dir}, 100*time.Millisecond, func(paths []string) {
+			changes <- paths
+		}))
+		sub := filepath.Join(dir, "sub")
+		assert.NoError(t, os.Mkdir(sub, 0755))
+		select {
+		case paths := <-changes: // the directory is watched before its creation is reported
+			assert.Equal(t, []string{sub}, paths)
+		case <-time.After(5 * time.Second):
+			t.Fatal("new directory not reported")
+		}
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.mp3"), nil, 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(sub, "b.mp3"), nil, 0644))
+		select {
+		case paths := <-changes:
+			assert.Contains(t, paths, filepath.Join(dir, "a.mp3"))
+			assert.Contains(t, paths, filepath.Join(sub, "b.mp3"))
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change reported")
+		}
+		select {
+		case paths := <-changes:
+			t.Fatalf("unexpected change %v", paths)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+	t.Run("detect moves", func(t *testing.T) {
+		modTime := time.Unix(1000, 0)
+		old := map[string]libraryTrack{
+			"/a/1.mp3":    {Path: "/a/1.mp3", Size: 10, ModTime: modTime, Title: "One"},
+			"/a/twin.mp3": {Path: "/a/twin.mp3", Size: 20, ModTime: modTime},
+			"/a/gone.mp3": {Path: "/a/gone.mp3", Size: 30, ModTime: modTime},
+			"/a/kept.mp3": {Path: "/a/kept.mp3", Size: 20, ModTime: modTime},
+		}
+		tracks := map[string]libraryTrack{
+			"/b/01 One.mp3": {Path: "/b/01 One.mp3", Size: 10, ModTime: modTime, Title: "One"},
+			"/b/twin1.mp3":  {Path: "/b/twin1.mp3", Size: 20, ModTime: modTime},
+			"/b/twin2.mp3":  {Path: "/b/twin2.mp3", Size: 20, ModTime: modTime},
+			"/a/kept.mp3":   {Path: "/a/kept.mp3", Size: 20, ModTime: modTime},
+		}
+		assert.Equal(t, map[string]string{"/a/1.mp3": "/b/01 One.mp3"}, detectMoves(old, tracks))
+	})
+	t.Run("carry history of moved tracks", func(t *testing.T) {
+		added := time.Unix(1000, 0)
+		rg := &ReplayGain{TrackGain: -3, HasTrackGain: true}
+		old := map[string]libraryTrack{"/a/1.mp3": {Path: "/a/1.mp3", Added: added, Loudness: -15, ReplayGain: rg}}
+		tracks := map[string]libraryTrack{"/b/1.mp3": {Path: "/b/1.mp3", Added: time.Now()}}
+		carryMoves(map[string]string{"/a/1.mp3": "/b/1.mp3"}, old, tracks)
+		assert.Equal(t, libraryTrack{Path: "/b/1.mp3", Added: added, Loudness: -15, ReplayGain: rg}, tracks["/b/1.mp3"])
+	})
+	t.Run("relocate songs", func(t *testing.T) {
+		songs := []song{{path: "/a/1.mp3", name: "1.mp3"}, {path: "/a/2.mp3", name: "Two"}, {path: "/a/3.mp3", name: "3.mp3"}}
+		assert.True(t, relocateSongs(songs, map[string]string{"/a/1.mp3": "/b/x.mp3", "/a/2.mp3": "/b/y.mp3"}))
+		assert.Equal(t, []song{{path: "/b/x.mp3", name: "x.mp3"}, {path: "/b/y.mp3", name: "Two"}, {path: "/a/3.mp3", name: "3.mp3"}}, songs)
+		assert.False(t, relocateSongs(songs, map[string]string{"/a/1.mp3": "/c/1.mp3"}))
+	})
+}
@@ -0,0 +1,156 @@
+package player
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/gorgemul/musicplayer/engine"
+	"image"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// peaksPerSecond is the resolution of the waveform, a peak is the loudest sample of its 50ms
+const peaksPerSecond = 20
+
+// readPeaks decode the whole file, a peak is 0 for silence to 255 for full scale
+func readPeaks(path string) ([]uint8, error) {
+	streamer, format, err := engine.Decode(path)
+	if err != nil {
+		return nil, err
+	}
+	defer streamer.Close()
+	var peaks []uint8
+	samples := make([][2]float64, format.SampleRate.N(time.Second/peaksPerSecond))
+	var peak float64
+	filled := 0
+	for {
+		n, ok := streamer.Stream(samples[filled:])
+		for _, sample := range samples[filled : filled+n] {
+			peak = max(peak, math.Abs(sample[0]), math.Abs(sample[1]))
+		}
+		filled += n
+		if filled == len(samples) || (!ok && filled > 0) {
+			peaks = append(peaks, uint8(math.Round(min(peak, 1)*255)))
+			peak, filled = 0, 0
+		}
+		if !ok {
+			break
+		}
+	}
+	return peaks, streamer.Err()
+}
+
+// readCachedPeaks read the peaks from the disk cache, the file is only decoded when it is not cached yet
+func readCachedPeaks(path string) ([]uint8, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cacheDir("waveforms")
+	if err != nil {
+		return readPeaks(path)
+	}
+	cached := filepath.Join(dir, cacheKey(path, info)+".peaks")
+	if peaks, err := os.ReadFile(cached); err == nil {
+		return peaks, nil
+	}
+	peaks, err := readPeaks(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cached, peaks, 0644); err != nil {
+		log.Println("write waveform", err)
+	}
+	return peaks, nil
+}
+
+// trackPeaks cut the peaks of the file down to the song, a virtual track of a cue sheet is a part of its file
+func trackPeaks(peaks []uint8, start, end time.Duration) []uint8 {
+	from := min(int(start.Seconds()*peaksPerSecond), len(peaks))
+	to := len(peaks)
+	if end > 0 {
+		to = min(max(int(end.Seconds()*peaksPerSecond), from), len(peaks))
+	}
+	return peaks[from:to]
+}
+
+// waveform draw the peaks of the current song with the played part shaded, tapping it call onTapped with the ratio of the song tapped
+type waveform struct {
+	widget.BaseWidget
+	peaks    []uint8
+	progress float64 // played ratio, 0 to 1
+	onTapped func(ratio float64)
+	raster   *canvas.Raster
+}
+
+func newWaveform(onTapped func(ratio float64)) *waveform {
+	w := &waveform{onTapped: onTapped}
+	w.raster = canvas.NewRaster(w.draw)
+	w.raster.SetMinSize(fyne.NewSize(200, 48))
+	w.ExtendBaseWidget(w)
+	return w
+}
+
+func (w *waveform) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(w.raster)
+}
+
+func (w *waveform) setPeaks(peaks []uint8) {
+	w.peaks = peaks
+	w.Refresh()
+}
+
+// setProgress shade the played ratio, the waveform is only drawn again when the shade grow by a pixel
+func (w *waveform) setProgress(ratio float64) {
+	width := float64(w.Size().Width)
+	moved := int(w.progress*width) != int(ratio*width)
+	w.progress = ratio
+	if moved {
+		w.Refresh()
+	}
+}
+
+func (w *waveform) Tapped(e *fyne.PointEvent) {
+	if w.onTapped != nil && w.Size().Width > 0 {
+		w.onTapped(min(max(float64(e.Position.X/w.Size().Width), 0), 1))
+	}
+}
+
+func (w *waveform) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
+
+// draw a vertical line by pixel column, as high as the loudest peak of the column relative to the loudest of the song
+func (w *waveform) draw(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if len(w.peaks) == 0 || width == 0 {
+		return img
+	}
+	highest := slices.Max(w.peaks)
+	if highest == 0 {
+		return img
+	}
+	played, rest := theme.Color(theme.ColorNamePrimary), theme.Color(theme.ColorNameDisabled)
+	playedWidth := int(w.progress * float64(width))
+	middle := height / 2
+	for x := range width {
+		from := x * len(w.peaks) / width
+		to := max((x+1)*len(w.peaks)/width, from+1)
+		half := int(float64(slices.Max(w.peaks[from:to])) / float64(highest) * float64(middle))
+		c := rest
+		if x < playedWidth {
+			c = played
+		}
+		for y := middle - half; y <= middle+half && y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,78 @@
+package player
+
+import (
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/wav"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTone write a wav of a loud second followed by a silent one
+func writeTone(t *testing.T, path string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	format := beep.Format{SampleRate: 8000, NumChannels: 2, Precision: 2}
+	n := 0
+	tone := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if n >= 16000 {
+			return 0, false
+		}
+		count := min(len(samples), 16000-n)
+		for i := range samples[:count] {
+			value := 0.0
+			if n+i < 8000 {
+				value = 0.8
+			}
+			samples[i] = [2]float64{value, -value}
+		}
+		n += count
+		return count, true
+	})
+	assert.NoError(t, wav.Encode(f, tone, format))
+}
+
+func TestWaveform(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	audio := filepath.Join(t.TempDir(), "tone.wav")
+	writeTone(t, audio)
+
+	t.Run("peaks", func(t *testing.T) {
+		peaks, err := readPeaks(audio)
+		assert.NoError(t, err)
+		assert.Len(t, peaks, 2*peaksPerSecond)
+		for _, peak := range peaks[:peaksPerSecond] {
+			assert.Greater(t, peak, uint8(0))
+		}
+		for _, peak := range peaks[peaksPerSecond:] {
+			assert.Zero(t, peak)
+		}
+	})
+	t.Run("cached per file", func(t *testing.T) {
+		peaks, err := readCachedPeaks(audio)
+		assert.NoError(t, err)
+		dir, err := cacheDir("waveforms")
+		assert.NoError(t, err)
+		cached, _ := os.ReadDir(dir)
+		assert.Len(t, cached, 1)
+		again, err := readCachedPeaks(audio)
+		assert.NoError(t, err)
+		assert.Equal(t, peaks, again)
+		cached, _ = os.ReadDir(dir)
+		assert.Len(t, cached, 1)
+	})
+	t.Run("unreadable file", func(t *testing.T) {
+		_, err := readCachedPeaks(filepath.Join(t.TempDir(), "missing.wav"))
+		assert.Error(t, err)
+	})
+	t.Run("track of a cue sheet", func(t *testing.T) {
+		peaks := make([]uint8, 10*peaksPerSecond)
+		assert.Len(t, trackPeaks(peaks, 0, 0), 10*peaksPerSecond)
+		assert.Len(t, trackPeaks(peaks, 2*time.Second, 5*time.Second), 3*peaksPerSecond)
+		assert.Len(t, trackPeaks(peaks, 8*time.Second, 0), 2*peaksPerSecond)
+		assert.Empty(t, trackPeaks(peaks, 20*time.Second, 30*time.Second))
+	})
+}
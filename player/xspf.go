@@ -0,0 +1,201 @@
+package player
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+/*
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+<trackList>
+<track>
+<location>file:///music/01.mp3</location>
+<title>...</title>
+<creator>...</creator>
+<album>...</album>
+<trackNum>...</trackNum>
+<duration>milliseconds</duration>
+<extension application="...">...</extension>
+</track>
+</trackList>
+</playlist>
+*/
+func readXSPF(r io.Reader, base string) ([]playlistItem, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		items      []playlistItem
+		namespaces = map[string]string{} // prefix declared on the root, needed by the preserved raw elements
+	)
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "playlist":
+			for _, attr := range start.Attr {
+				if attr.Name.Space == "xmlns" {
+					namespaces[attr.Name.Local] = attr.Value
+				}
+			}
+		case "track":
+			item, err := readXSPFTrack(decoder, data, base, namespaces)
+			if err != nil {
+				return nil, err
+			}
+			if item.path != "" {
+				items = append(items, item)
+			}
+		}
+	}
+}
+
+func readXSPFTrack(decoder *xml.Decoder, data []byte, base string, namespaces map[string]string) (playlistItem, error) {
+	item := playlistItem{duration: -1}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			return item, err
+		}
+		switch token := token.(type) {
+		case xml.EndElement:
+			return item, nil
+		case xml.StartElement:
+			if token.Name.Space != xspfNamespace && token.Name.Space != "" {
+				if err := decoder.Skip(); err != nil {
+					return item, err
+				}
+				item.extensions = append(item.extensions, declareNamespaces(string(data[offset:decoder.InputOffset()]), namespaces))
+				continue
+			}
+			var text string
+			switch token.Name.Local {
+			case "location", "title", "creator", "album", "trackNum", "duration":
+				if err := decoder.DecodeElement(&text, &token); err != nil {
+					return item, err
+				}
+			default:
+				if err := decoder.Skip(); err != nil {
+					return item, err
+				}
+				item.extensions = append(item.extensions, declareNamespaces(string(data[offset:decoder.InputOffset()]), namespaces))
+				continue
+			}
+			text = strings.TrimSpace(text)
+			switch token.Name.Local {
+			case "location":
+				if item.path == "" {
+					item.path = resolveXSPFLocation(base, text)
+				}
+			case "title":
+				item.title = text
+			case "creator":
+				item.artist = text
+			case "album":
+				item.album = text
+			case "trackNum":
+				if n, err := strconv.Atoi(text); err == nil && n > 0 {
+					item.track = n
+				}
+			case "duration":
+				if ms, err := strconv.ParseInt(text, 10, 64); err == nil && ms >= 0 {
+					item.duration = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+	}
+}
+
+func writeXSPF(w io.Writer, base string, items []playlistItem) error {
+	bw := bufio.NewWriter(w)
+	element := func(name, value string) {
+		if value == "" {
+			return
+		}
+		fmt.Fprintf(bw, "      <%s>", name)
+		xml.EscapeText(bw, []byte(value))
+		fmt.Fprintf(bw, "</%s>\n", name)
+	}
+	fmt.Fprint(bw, xml.Header)
+	fmt.Fprintf(bw, "<playlist version=\"1\" xmlns=\"%s\">\n", xspfNamespace)
+	fmt.Fprintln(bw, "  <trackList>")
+	for _, item := range items {
+		fmt.Fprintln(bw, "    <track>")
+		element("location", xspfLocation(base, item.path))
+		element("title", item.title)
+		element("creator", item.artist)
+		element("album", item.album)
+		if item.track > 0 {
+			element("trackNum", strconv.Itoa(item.track))
+		}
+		if item.duration >= 0 {
+			element("duration", strconv.FormatInt(item.duration.Milliseconds(), 10))
+		}
+		for _, extension := range item.extensions {
+			fmt.Fprintf(bw, "      %s\n", extension)
+		}
+		fmt.Fprintln(bw, "    </track>")
+	}
+	fmt.Fprintln(bw, "  </trackList>")
+	fmt.Fprintln(bw, "</playlist>")
+	return bw.Flush()
+}
+
+func resolveXSPFLocation(base, location string) string {
+	if strings.HasPrefix(location, "file:") {
+		if u, err := url.Parse(location); err == nil {
+			return filepath.FromSlash(u.Path)
+		}
+	}
+	if unescaped, err := url.PathUnescape(location); err == nil {
+		location = unescaped
+	}
+	return resolvePlaylistPath(base, location)
+}
+
+// xspfLocation use relative uri for path below the playlist file, otherwise absolute file:// uri
+func xspfLocation(base, path string) string {
+	if rel := relativePlaylistPath(base, path); rel != path {
+		return (&url.URL{Path: rel}).String()
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// declareNamespaces copy the root namespace declarations used by the raw element onto itself, so it stay valid when written elsewhere
+func declareNamespaces(raw string, namespaces map[string]string) string {
+	end := strings.IndexAny(raw, " \t\r\n/>")
+	if end == -1 {
+		return raw
+	}
+	var declarations string
+	for _, prefix := range slices.Sorted(maps.Keys(namespaces)) {
+		used := strings.Contains(raw, "<"+prefix+":") || strings.Contains(raw, " "+prefix+":")
+		if used && !strings.Contains(raw, "xmlns:"+prefix+"=") {
+			declarations += fmt.Sprintf(" xmlns:%s=\"%s\"", prefix, namespaces[prefix])
+		}
+	}
+	return raw[:end] + declarations + raw[end:]
+}
@@ -0,0 +1,75 @@
+package player
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXSPF(t *testing.T) {
+	base := filepath.FromSlash("/music/lists")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist xmlns="http://xspf.org/ns/0/" xmlns:vlc="http://www.videolan.org/vlc/playlist/ns/0/" version="1">
+	<title>Shared</title>
+	<trackList>
+		<track>
+			<location>file:///music/Soft%20Tags/01.mp3</location>
+			<title>They Live By Night</title>
+			<creator>Soft Tags</creator>
+			<album>Demo &amp; More</album>
+			<trackNum>1</trackNum>
+			<duration>215000</duration>
+			<extension application="http://www.videolan.org/vlc/playlist/0">
+				<vlc:id>0</vlc:id>
+			</extension>
+		</track>
+		<track>
+			<location>../other/02%20b.wav</location>
+		</track>
+		<track>
+			<title>no location</title>
+		</track>
+	</trackList>
+</playlist>`
+	want := []playlistItem{
+		{
+			path:     filepath.FromSlash("/music/Soft Tags/01.mp3"),
+			title:    "They Live By Night",
+			artist:   "Soft Tags",
+			album:    "Demo & More",
+			track:    1,
+			duration: 215 * time.Second,
+			extensions: []string{`<extension xmlns:vlc="http://www.videolan.org/vlc/playlist/ns/0/" application="http://www.videolan.org/vlc/playlist/0">
+				<vlc:id>0</vlc:id>
+			</extension>`},
+		},
+		{path: filepath.FromSlash("/music/other/02 b.wav"), duration: -1},
+	}
+
+	t.Run("read", func(t *testing.T) {
+		items, err := readPlaylist(strings.NewReader(content), filepath.Join(base, "a.xspf"))
+		assert.NoError(t, err)
+		assert.Equal(t, want, items)
+	})
+	t.Run("round trip", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, writePlaylist(buf, filepath.Join(base, "b.xspf"), want))
+		assert.Contains(t, buf.String(), "<location>file:///music/Soft%20Tags/01.mp3</location>")
+		assert.Contains(t, buf.String(), "<album>Demo &amp; More</album>")
+		items, err := readPlaylist(buf, filepath.Join(base, "b.xspf"))
+		assert.NoError(t, err)
+		assert.Equal(t, want, items)
+	})
+	t.Run("relative location", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, writePlaylist(buf, filepath.Join(base, "c.xspf"), []playlistItem{{path: filepath.Join(base, "sub dir", "03.mp3"), duration: -1}}))
+		assert.Contains(t, buf.String(), "<location>sub%20dir/03.mp3</location>")
+	})
+	t.Run("invalid xml", func(t *testing.T) {
+		_, err := readPlaylist(strings.NewReader("<playlist><trackList><track>"), "a.xspf")
+		assert.Error(t, err)
+	})
+}